@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/lockout"
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/tokens"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// Minimum length of the key used to sign the access tokens.
+const AuthSecretMinLength = 32
+
+// Creates the manager of the bearer tokens, the refresh tokens
+// are kept in the provided storage (the one of the sessions).
+func NewTokenManager(config Config, storage fiber.Storage, logger *slog.Logger) (*tokens.Manager, error) {
+	secret := []byte(config.Auth.Secret)
+
+	if len(secret) == 0 {
+		secret = make([]byte, AuthSecretMinLength)
+
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Warn("auth secret not configured, the tokens won't survive a restart")
+	}
+
+	return tokens.New(storage, tokens.Options{
+		Secret:     secret,
+		AccessTTL:  time.Duration(config.Auth.AccessTokenTTL) * time.Second,
+		RefreshTTL: time.Duration(config.Auth.RefreshTokenTTL) * time.Second,
+	}), nil
+}
+
+// Handler for authenticating the user with tokens
+// instead of a session cookie.
+func (g *Global) AuthToken(c *fiber.Ctx) error {
+	credentials, err := ReadBodyFromRequest[models.User](c)
+	if err != nil {
+		return err
+	}
+
+	v := models.CheckLogin(credentials)
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	id, err := g.login(c, credentials)
+	if err != nil {
+		return err
+	}
+
+	pair, err := g.Tokens.Issue(id)
+	if err != nil {
+		return fmt.Errorf("issue tokens: %w", err)
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, pair)
+}
+
+// Handler for getting a new pair of tokens in exchange for
+// a refresh token, which can't be used again afterwards.
+func (g *Global) AuthRefresh(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[RefreshTokenRequest](c)
+	if err != nil {
+		return err
+	}
+
+	pair, err := g.Tokens.Refresh(request.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("refresh tokens: %w", err)
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, pair)
+}
+
+// Handler for revoking a refresh token, unknown
+// tokens are treated as already revoked.
+func (g *Global) AuthRevoke(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[RefreshTokenRequest](c)
+	if err != nil {
+		return err
+	}
+
+	err = g.Tokens.Revoke(request.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "token.revoked", nil))
+}
+
+// Creates the login brute-force protection, the counters
+// are kept in the provided storage (the one of the sessions).
+func NewLoginLimiter(config Config, storage fiber.Storage) *lockout.Limiter {
+	return lockout.New(storage, lockout.Options{
+		FreeAttempts: config.Login.FreeAttempts,
+		MaxAttempts:  config.Login.MaxAttempts,
+		BaseDelay:    time.Duration(config.Login.BaseDelay) * time.Second,
+		Lockout:      time.Duration(config.Login.Lockout) * time.Second,
+	})
+}
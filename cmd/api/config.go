@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables overriding the configuration.
+const ConfigEnvPrefix = "NAMELESS_"
+
+// Replaces the values of the secrets, see `RedactConfig`.
+const RedactedValue = "REDACTED"
+
+const configUsage = `Usage: api config print [-config=<path/to/config/file>] [-set=<key>=<value>...] [-redact]
+
+	print  prints the configuration resulting from the defaults, the file,
+	       the environment variables NAMELESS_* and the flags -set
+	-redact  replaces the values of the secrets`
+
+// ConfigOverrides are the configuration values set with the flag -set
+// as `key=value`, the key is the path of the field made of its JSON
+// names separated by dots, for example `database.password`.
+type ConfigOverrides []string
+
+func (o *ConfigOverrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *ConfigOverrides) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return errors.New("expected key=value")
+	}
+
+	*o = append(*o, value)
+	return nil
+}
+
+// Defines the flags selecting the configuration on the flag set,
+// they're shared by the server and the subcommands.
+func DefineConfigFlags(flagSet *flag.FlagSet) *Flags {
+	flags := &Flags{}
+
+	flagSet.StringVar(&flags.ConfigPath, "config", "", "The path of the configuration file (JSON, YAML or TOML)")
+	flagSet.Var(&flags.Overrides, "set", "Sets a configuration value as key=value, it can be repeated")
+
+	return flags
+}
+
+// Defines, parses and returns the command line flags.
+func GetFlags() Flags {
+	flags := DefineConfigFlags(flag.CommandLine)
+
+	flag.Parse()
+
+	return *flags
+}
+
+// Loads the configuration from its layers, each one overrides
+// the values of the previous ones:
+//
+//   - The defaults (see `DefaultConfig`).
+//   - The file of `Flags.ConfigPath` if any, its format (JSON, YAML
+//     or TOML) is chosen by its extension.
+//   - The environment variables `NAMELESS_*` of environ, the
+//     `os.Environ` format (see `ConfigEnvName`).
+//   - The values set with the flag -set (see `ConfigOverrides`).
+//
+// The unknown fields, variables and keys are reported as errors.
+func LoadConfig(flags Flags, environ []string) (Config, error) {
+	config := DefaultConfig()
+
+	if flags.ConfigPath != "" {
+		err := loadConfigFile(&config, flags.ConfigPath)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err := loadConfigEnv(&config, environ)
+	if err != nil {
+		return Config{}, err
+	}
+
+	for _, override := range flags.Overrides {
+		key, value, _ := strings.Cut(override, "=")
+
+		err := setConfigValue(&config, key, value)
+		if err != nil {
+			return Config{}, fmt.Errorf("-set %s: %w", key, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Loads and validates the configuration, the process
+// exits if the configuration can't be used.
+func MustLoadConfig(flags Flags) Config {
+	config, err := LoadConfig(flags, os.Environ())
+	if err != nil {
+		log.Fatalln("failed loading config: ", err)
+	}
+
+	configValidationErrors := ValidateConfig(config)
+	if configValidationErrors != nil {
+		fmt.Println("Configuration validation failed with the following errors:")
+		fmt.Println()
+
+		for _, err := range configValidationErrors {
+			fmt.Printf("\t- %s\n", err)
+		}
+
+		fmt.Println()
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// Reads the configuration file into config, the YAML and TOML
+// files are converted to JSON, so every format is decoded by
+// the JSON names of the fields.
+func loadConfigFile(config *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]any
+
+	switch extension := strings.ToLower(filepath.Ext(path)); extension {
+	case ".json":
+
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+		if err != nil {
+			return err
+		}
+
+		data, err = json.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+	case ".toml":
+		_, err = toml.Decode(string(data), &values)
+		if err != nil {
+			return err
+		}
+
+		data, err = json.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown configuration file format %q", extension)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(config)
+}
+
+// Sets the values of the environment variables `NAMELESS_*`.
+func loadConfigEnv(config *Config, environ []string) error {
+	variables := map[string]string{}
+
+	for _, variable := range environ {
+		name, value, ok := strings.Cut(variable, "=")
+		if ok && strings.HasPrefix(name, ConfigEnvPrefix) {
+			variables[name] = value
+		}
+	}
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	used := map[string]bool{}
+
+	for _, key := range ConfigKeys() {
+		prefix, suffix, isMap := strings.Cut(ConfigEnvName(key), "*")
+
+		for _, name := range names {
+			var fieldKey string
+
+			switch {
+			case !isMap && name == prefix:
+				fieldKey = key
+
+			case isMap && len(name) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix):
+				mapKey := strings.ToLower(name[len(prefix) : len(name)-len(suffix)])
+				fieldKey = strings.Replace(key, "*", mapKey, 1)
+
+			default:
+				continue
+			}
+
+			err := setConfigValue(config, fieldKey, variables[name])
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			used[name] = true
+		}
+	}
+
+	for _, name := range names {
+		if !used[name] {
+			return fmt.Errorf("unknown environment variable %s", name)
+		}
+	}
+
+	return nil
+}
+
+// Returns the keys of the fields of the configuration, the keys of
+// the maps are written as `*`, for example `rateLimit.*.limit`.
+func ConfigKeys() []string {
+	return configKeys(reflect.TypeOf(Config{}), "")
+}
+
+func configKeys(t reflect.Type, key string) []string {
+	switch t.Kind() {
+	case reflect.Struct:
+		var keys []string
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+
+			if !field.IsExported() {
+				continue
+			}
+
+			// The fields of the embedded structs are promoted.
+			if isPromoted(field) {
+				keys = append(keys, configKeys(field.Type, key)...)
+				continue
+			}
+
+			keys = append(keys, configKeys(field.Type, joinConfigKey(key, jsonFieldName(field)))...)
+		}
+
+		return keys
+
+	case reflect.Map:
+		return configKeys(t.Elem(), joinConfigKey(key, "*"))
+	}
+
+	return []string{key}
+}
+
+// Returns the name of the environment variable of the configuration
+// key, for example `NAMELESS_AUTH_ACCESS_TOKEN_TTL` for `auth.accessTokenTtl`.
+func ConfigEnvName(key string) string {
+	var name strings.Builder
+
+	name.WriteString(ConfigEnvPrefix)
+
+	var previous rune
+
+	for _, r := range key {
+		switch {
+		case r == '.':
+			name.WriteRune('_')
+
+		case unicode.IsUpper(r) && (unicode.IsLower(previous) || unicode.IsDigit(previous)):
+			name.WriteRune('_')
+			name.WriteRune(r)
+
+		default:
+			name.WriteRune(unicode.ToUpper(r))
+		}
+
+		previous = r
+	}
+
+	return name.String()
+}
+
+// Sets the field identified by key (see `ConfigOverrides`) to
+// the value, which is parsed according to the type of the field.
+func setConfigValue(config *Config, key string, value string) error {
+	return setValue(reflect.ValueOf(config).Elem(), strings.Split(key, "."), value)
+}
+
+func setValue(v reflect.Value, path []string, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		if len(path) == 0 {
+			return errors.New("the key is a section, not a value")
+		}
+
+		field, ok := fieldByJSONName(v, path[0])
+		if !ok {
+			return fmt.Errorf("unknown key %q", path[0])
+		}
+
+		return setValue(field, path[1:], value)
+
+	case reflect.Map:
+		if len(path) == 0 {
+			return errors.New("the key is a section, not a value")
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		// The values of the maps are not addressable, so
+		// the value is modified as a copy and put back.
+		mapKey := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+
+		if current := v.MapIndex(mapKey); current.IsValid() {
+			elem.Set(current)
+		}
+
+		err := setValue(elem, path[1:], value)
+		if err != nil {
+			return err
+		}
+
+		v.SetMapIndex(mapKey, elem)
+		return nil
+	}
+
+	if len(path) > 0 {
+		return fmt.Errorf("unknown key %q", path[0])
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+
+		v.SetBool(parsed)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+
+		v.SetInt(parsed)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a positive integer, got %q", value)
+		}
+
+		v.SetUint(parsed)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		// The lists are separated by commas.
+		var values []string
+
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+
+		v.Set(reflect.ValueOf(values))
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Returns the field of the struct named name in JSON, the names
+// are compared ignoring the case, as `encoding/json` does.
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if isPromoted(field) {
+			promoted, ok := fieldByJSONName(v.Field(i), name)
+			if ok {
+				return promoted, true
+			}
+
+			continue
+		}
+
+		if strings.EqualFold(jsonFieldName(field), name) {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// Reports whether the fields of the field are promoted to its struct in JSON.
+func isPromoted(field reflect.StructField) bool {
+	return field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func joinConfigKey(key string, name string) string {
+	if key == "" {
+		return name
+	}
+
+	return key + "." + name
+}
+
+// Returns a copy of the configuration with the values of
+// the secrets (the fields tagged `redact:"true"`) replaced.
+func RedactConfig(config Config) Config {
+	redact(reflect.ValueOf(&config).Elem())
+	return config
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+
+		switch {
+		case v.Type().Field(i).Tag.Get("redact") == "true":
+			if field.String() != "" {
+				field.SetString(RedactedValue)
+			}
+
+		case field.Kind() == reflect.Struct:
+			redact(field)
+		}
+	}
+}
+
+// Runs the subcommand `config`, which prints the effective
+// configuration. It returns the exit code.
+func RunConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Println(configUsage)
+		return 2
+	}
+
+	flagSet := flag.NewFlagSet("config", flag.ExitOnError)
+	flags := DefineConfigFlags(flagSet)
+	redacted := flagSet.Bool("redact", false, "Replaces the values of the secrets")
+	flagSet.Parse(args[1:])
+
+	config, err := LoadConfig(*flags, os.Environ())
+	if err != nil {
+		fmt.Println("An error occured while loading the configuration:")
+		fmt.Println()
+
+		fmt.Println(err)
+
+		fmt.Println()
+		return 1
+	}
+
+	if *redacted {
+		config = RedactConfig(config)
+	}
+
+	data, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	fmt.Println(string(data))
+
+	return 0
+}
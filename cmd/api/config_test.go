@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/password"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"config.yaml": "server:\n  addr: \":8080\"\nauth:\n  secret: from-file\nrateLimit:\n  user:\n    limit: 5\n    window: 10\n",
+		"config.toml": "appdata = \"./data\"\n\n[server]\naddr = \":8080\"\n\n[auth]\nsecret = \"from-file\"\n",
+		"config.json": `{"server": {"addr": ":8080"}, "auth": {"secret": "from-file"}}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		err := os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The file overrides the defaults.
+		config, err := LoadConfig(Flags{ConfigPath: path}, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if config.Server.Addr != ":8080" || config.Auth.Secret != "from-file" || config.Match.Duration != 300 {
+			t.Errorf("%s: unexpected config %+v", name, config)
+		}
+	}
+
+	// The environment variables override the file,
+	// and the flag -set overrides both.
+	environ := []string{
+		"NAMELESS_AUTH_SECRET=from-env",
+		"NAMELESS_DATABASE_PASSWORD=secret",
+		"NAMELESS_AUTH_ACCESS_TOKEN_TTL=60",
+		"NAMELESS_RATE_LIMIT_USER_LIMIT=7",
+		"NAMELESS_SERVER_ADDR=:9090",
+		"NAMELESS_SERVER_TRUSTED_PROXIES=10.0.0.1, 10.1.0.0/16",
+		"HOME=/root",
+	}
+
+	flags := Flags{
+		ConfigPath: filepath.Join(dir, "config.yaml"),
+		Overrides:  ConfigOverrides{"server.addr=:7070", "password.requireUppercase=true"},
+	}
+
+	config, err := LoadConfig(flags, environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Auth.Secret != "from-env" || config.Database.Password != "secret" || config.Auth.AccessTokenTTL != 60 {
+		t.Errorf("expected the values of the environment, got %+v", config)
+	}
+
+	if rule := config.RateLimit[RateLimitGroupUser]; rule.Limit != 7 || rule.Window != 10 {
+		t.Errorf("expected the rule of the environment merged with the file, got %+v", rule)
+	}
+
+	if !reflect.DeepEqual(config.Server.TrustedProxies, []string{"10.0.0.1", "10.1.0.0/16"}) {
+		t.Errorf("expected the list of the environment, got %v", config.Server.TrustedProxies)
+	}
+
+	if config.Server.Addr != ":7070" || !config.Password.RequireUppercase {
+		t.Errorf("expected the values of the flags, got %+v", config)
+	}
+
+	redacted := RedactConfig(config)
+	if redacted.Auth.Secret != RedactedValue || redacted.Database.Password != RedactedValue || redacted.Redis.Password != "" {
+		t.Errorf("expected the secrets redacted, got %+v", redacted)
+	}
+
+	if config.Auth.Secret != "from-env" {
+		t.Errorf("expected the config not modified by the redaction, got %+v", config)
+	}
+
+	// The unknown names are reported.
+	_, err = LoadConfig(Flags{}, []string{"NAMELESS_AUTH_SECRETS=typo"})
+	if err == nil {
+		t.Error("expected an error for the unknown environment variable")
+	}
+
+	_, err = LoadConfig(Flags{Overrides: ConfigOverrides{"auth.ttl=1"}}, nil)
+	if err == nil {
+		t.Error("expected an error for the unknown key")
+	}
+
+	_, err = LoadConfig(Flags{}, []string{"NAMELESS_MATCH_TIMEOUT=soon"})
+	if err == nil {
+		t.Error("expected an error for the value not valid")
+	}
+}
+
+func TestValidateConfigBodyLimit(t *testing.T) {
+	// Reports whether the size of the pictures was rejected.
+	rejected := func(bodyLimit int, maxSize int64) bool {
+		config := DefaultConfig()
+		config.Server.BodyLimit = bodyLimit
+		config.Images.MaxSize = maxSize
+
+		for _, err := range ValidateConfig(config) {
+			if strings.HasPrefix(err, "images: maxSize") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if !rejected(4*1024*1024, 4*1024*1024) {
+		t.Error("expected the pictures as big as the body limit rejected")
+	}
+
+	if !rejected(4*1024*1024, 4*1024*1024-MultipartOverhead+1) {
+		t.Error("expected the pictures without room for the multipart overhead rejected")
+	}
+
+	if rejected(4*1024*1024, 4*1024*1024-MultipartOverhead) {
+		t.Error("expected the pictures fitting the body limit with the overhead accepted")
+	}
+}
+
+func TestNewPasswordPolicy(t *testing.T) {
+	config := DefaultConfig()
+
+	// Only bcrypt limits the bytes of the passwords.
+	if maxBytes := NewPasswordPolicy(config).MaxBytes(); maxBytes != password.BcryptMaxBytes {
+		t.Errorf("bcrypt: expected %d max bytes, got %d", password.BcryptMaxBytes, maxBytes)
+	}
+
+	config.Hashing.Algorithm = hashing.AlgorithmArgon2id
+
+	if maxBytes := NewPasswordPolicy(config).MaxBytes(); maxBytes != 0 {
+		t.Errorf("argon2id: expected no max bytes, got %d", maxBytes)
+	}
+}
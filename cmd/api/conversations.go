@@ -0,0 +1,207 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Gets the conversation identified by the route parameter `id`,
+// it fails with `models.ErrNotParticipant` if the logged-in user
+// has not joined the conversation.
+func (g *Global) getJoinedConversation(c *fiber.Ctx) (models.Conversation, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return models.Conversation{}, models.ErrNoRecords
+	}
+
+	conversation, err := g.Database.ConversationManager.Get(id)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	if !conversation.HasParticipant(g.GetUserId(c)) {
+		return models.Conversation{}, models.ErrNotParticipant
+	}
+
+	return conversation, nil
+}
+
+// Checks the users identified by ids exist, it fails with
+// `models.ErrNoRecords` as soon as one of them doesn't.
+func (g *Global) checkUsersExist(ids ...int) error {
+	for _, id := range ids {
+		_, err := g.Database.UserManager.Get(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler for creating a conversation, the logged-in
+// user joins the conversation along with the participants.
+func (g *Global) ConversationNew(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[NewConversationRequest](c)
+	if err != nil {
+		return err
+	}
+
+	v := models.CheckConversationDuration(request.Duration)
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	userId := g.GetUserId(c)
+
+	// The logged-in user and the repeated ids are ignored.
+	participants := []int{userId}
+	seen := map[int]bool{userId: true}
+
+	for _, id := range request.Participants {
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		participants = append(participants, id)
+	}
+
+	err = g.checkUsersExist(participants[1:]...)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := g.Database.ConversationManager.New(request.Duration, participants, false)
+	if err != nil {
+		return err
+	}
+
+	g.Expiry.Schedule(conversation)
+
+	return SendSucessMessage(c, fiber.StatusCreated, conversation)
+}
+
+// Handler for listing the conversations
+// the logged-in user has joined.
+func (g *Global) ConversationList(c *fiber.Ctx) error {
+	conversations, err := g.Database.ConversationManager.ListForUser(g.GetUserId(c))
+	if err != nil {
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, conversations)
+}
+
+// Handler for getting a conversation.
+func (g *Global) ConversationGet(c *fiber.Ctx) error {
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, conversation)
+}
+
+// Handler for adding a participant to a conversation, the
+// participants of the matched conversations can't be added.
+func (g *Global) ConversationAddParticipant(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[ParticipantRequest](c)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	if conversation.Matched {
+		return models.ErrConversationMatched
+	}
+
+	if conversation.IsExpired(time.Now()) {
+		return models.ErrConversationExpired
+	}
+
+	err = g.checkUsersExist(request.UserId)
+	if err != nil {
+		return err
+	}
+
+	err = g.Database.ConversationManager.AddParticipant(conversation.Id, request.UserId)
+	if err != nil {
+		return err
+	}
+
+	g.Hub.Publish(append(conversation.Participants, request.UserId), realtime.Event{
+		Type: realtime.EventParticipantJoined,
+		Data: realtime.ParticipantEvent{
+			ConversationId: conversation.Id,
+			UserId:         request.UserId,
+		},
+	})
+
+	return SendSucessMessage(c, fiber.StatusCreated, g.Message(c, "conversation.participant_added", nil))
+}
+
+// Handler for removing a participant from a conversation,
+// participants can only remove themselves to leave the conversation.
+func (g *Global) ConversationRemoveParticipant(c *fiber.Ctx) error {
+	userId, err := c.ParamsInt("userId")
+	if err != nil {
+		return models.ErrNoRecords
+	}
+
+	if userId != g.GetUserId(c) {
+		return models.ErrParticipantNotSelf
+	}
+
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	err = g.Database.ConversationManager.RemoveParticipant(conversation.Id, userId)
+	if err != nil {
+		return err
+	}
+
+	// The removed participant is notified as well.
+	g.Hub.Publish(conversation.Participants, realtime.Event{
+		Type: realtime.EventParticipantLeft,
+		Data: realtime.ParticipantEvent{
+			ConversationId: conversation.Id,
+			UserId:         userId,
+		},
+	})
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "conversation.participant_removed", nil))
+}
+
+// Handler for ending a conversation.
+func (g *Global) ConversationEnd(c *fiber.Ctx) error {
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	err = g.Database.ConversationManager.End(conversation.Id)
+	if err != nil {
+		return err
+	}
+
+	g.Expiry.Cancel(conversation.Id)
+
+	g.Hub.Publish(conversation.Participants, realtime.Event{
+		Type: realtime.EventConversationEnded,
+		Data: realtime.ConversationEvent{
+			ConversationId: conversation.Id,
+		},
+	})
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "conversation.ended", nil))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestConversations(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	owner, ownerCookies := newLoggedInUser(t, app, g, "edwin")
+	friend, friendCookies := newLoggedInUser(t, app, g, "carlos")
+	stranger, strangerCookies := newLoggedInUser(t, app, g, "maria")
+
+	res := doRequest(t, app, ownerCookies, fiber.MethodPost, "/api/conversations", NewConversationRequest{
+		Duration:     -1,
+		Participants: []int{friend},
+	})
+	if res.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("duration not valid: expected status %d, got %d", fiber.StatusBadRequest, res.StatusCode)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, "/api/conversations", NewConversationRequest{
+		Duration:     300,
+		Participants: []int{friend, 999},
+	})
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("unknown participant: expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	// The repeated ids and the id of the owner are ignored.
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, "/api/conversations", NewConversationRequest{
+		Duration:     300,
+		Participants: []int{friend, owner, friend},
+	})
+	if res.StatusCode != fiber.StatusCreated {
+		t.Fatalf("create: expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+
+	conversation := decodeData[models.Conversation](t, res)
+	if !reflect.DeepEqual(conversation.Participants, []int{owner, friend}) {
+		t.Errorf("expected participants %v, got %v", []int{owner, friend}, conversation.Participants)
+	}
+
+	path := fmt.Sprintf("/api/conversations/%d", conversation.Id)
+
+	// Both participants see the conversation, the stranger doesn't.
+	for _, cookies := range []map[string]string{ownerCookies, friendCookies} {
+		res = doRequest(t, app, cookies, fiber.MethodGet, "/api/conversations", nil)
+		if list := decodeData[[]models.Conversation](t, res); len(list) != 1 || list[0].Id != conversation.Id {
+			t.Errorf("list: expected the conversation, got %+v", list)
+		}
+
+		res = doRequest(t, app, cookies, fiber.MethodGet, path, nil)
+		if res.StatusCode != fiber.StatusOK {
+			t.Errorf("get: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+		}
+	}
+
+	res = doRequest(t, app, strangerCookies, fiber.MethodGet, path, nil)
+	if res.StatusCode != fiber.StatusForbidden {
+		t.Errorf("get as stranger: expected status %d, got %d", fiber.StatusForbidden, res.StatusCode)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodGet, "/api/conversations/999", nil)
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("get unknown: expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, path+"/participants", ParticipantRequest{UserId: 999})
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("add unknown participant: expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	// The stranger joins, then leaves.
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, path+"/participants", ParticipantRequest{UserId: stranger})
+	if res.StatusCode != fiber.StatusCreated {
+		t.Errorf("add participant: expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+
+	res = doRequest(t, app, friendCookies, fiber.MethodDelete, fmt.Sprintf("%s/participants/%d", path, stranger), nil)
+	if res.StatusCode != fiber.StatusForbidden {
+		t.Errorf("remove other participant: expected status %d, got %d", fiber.StatusForbidden, res.StatusCode)
+	}
+
+	res = doRequest(t, app, strangerCookies, fiber.MethodGet, path, nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("get as participant: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, strangerCookies, fiber.MethodDelete, fmt.Sprintf("%s/participants/%d", path, stranger), nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("leave: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, strangerCookies, fiber.MethodGet, path, nil)
+	if res.StatusCode != fiber.StatusForbidden {
+		t.Errorf("get after leaving: expected status %d, got %d", fiber.StatusForbidden, res.StatusCode)
+	}
+
+	// Ended conversations don't take messages.
+	res = doRequest(t, app, friendCookies, fiber.MethodPost, path+"/end", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("end: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodGet, path, nil)
+	if ended := decodeData[models.Conversation](t, res); ended.EndedAt == nil {
+		t.Error("expected the conversation to be ended")
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, path+"/end", nil)
+	if res.StatusCode != fiber.StatusConflict {
+		t.Errorf("end twice: expected status %d, got %d", fiber.StatusConflict, res.StatusCode)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, path+"/messages", NewMessageRequest{Content: "hello"})
+	if res.StatusCode != fiber.StatusConflict {
+		t.Errorf("message after end: expected status %d, got %d", fiber.StatusConflict, res.StatusCode)
+	}
+
+	// The participants of matched conversations can't be added.
+	matched, err := g.Database.ConversationManager.New(300, []int{owner, friend}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res = doRequest(t, app, ownerCookies, fiber.MethodPost, fmt.Sprintf("/api/conversations/%d/participants", matched.Id), ParticipantRequest{UserId: stranger})
+	if res.StatusCode != fiber.StatusForbidden {
+		t.Errorf("add participant to matched: expected status %d, got %d", fiber.StatusForbidden, res.StatusCode)
+	}
+
+	// The routes require a session.
+	res = doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/conversations", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("anonymous: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+}
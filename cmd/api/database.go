@@ -4,8 +4,107 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
+
+	"github.com/Edwing123/udem-chat-app/pkg/migrations"
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/models/memory"
+	sqlserver "github.com/Edwing123/udem-chat-app/pkg/models/sql-server"
+	"github.com/Edwing123/udem-chat-app/pkg/models/sqlite"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/password"
+	"golang.org/x/exp/slog"
 )
 
+// Creates the manager of the password hashes, the
+// new hashes are produced by the configured algorithm.
+func NewHasher(config Config) *hashing.Manager {
+	if config.Hashing.Algorithm == hashing.AlgorithmArgon2id {
+		return hashing.New(hashing.Argon2id{
+			Time:    uint32(config.Hashing.Argon2id.Time),
+			Memory:  uint32(config.Hashing.Argon2id.Memory),
+			Threads: uint8(config.Hashing.Argon2id.Threads),
+		})
+	}
+
+	return hashing.New(hashing.Bcrypt{Cost: config.Hashing.BcryptCost})
+}
+
+// Creates the password policy of the configuration, bcrypt ignores the
+// bytes past `password.BcryptMaxBytes`, so the longer passwords are
+// rejected when it's the algorithm hashing the passwords.
+func NewPasswordPolicy(config Config) password.Policy {
+	if config.Hashing.Algorithm == hashing.AlgorithmBcrypt {
+		return config.Password.LimitBytes(password.BcryptMaxBytes)
+	}
+
+	return config.Password
+}
+
+// Creates the implementation of `models.Database` selected by the
+// driver, the data of the driver `DriverMemory` is lost on exit.
+// The SQL database must be closed once the server is done with
+// it, it's nil for `DriverMemory`.
+//
+// It fails if the schema of the database is older than the one
+// expected by the binary, the pending migrations must be applied
+// first with the subcommand `migrate up`.
+func NewDatabase(config DatabaseConfig, hasher *hashing.Manager, logger *slog.Logger) (models.Database, *sql.DB, error) {
+	if config.Driver == DriverMemory {
+		logger.Warn("using in-memory database, data will be lost on exit")
+		return memory.New(hasher, logger), nil, nil
+	}
+
+	sqldb, migrator, err := OpenSQLDatabase(config)
+	if err != nil {
+		return models.Database{}, nil, err
+	}
+
+	err = migrator.Check()
+	if err != nil {
+		sqldb.Close()
+		return models.Database{}, nil, err
+	}
+
+	if config.Driver == DriverSQLite {
+		return sqlite.New(sqldb, hasher, logger), sqldb, nil
+	}
+
+	return sqlserver.New(sqldb, hasher, logger), sqldb, nil
+}
+
+// Opens the SQL database selected by the driver
+// and creates the migrator of its schema.
+func OpenSQLDatabase(config DatabaseConfig) (*sql.DB, *migrations.Migrator, error) {
+	var sqldb *sql.DB
+	var err error
+	var newMigrator func(*sql.DB) (*migrations.Migrator, error)
+
+	switch config.Driver {
+	case DriverSQLServer:
+		sqldb, err = NewSQLServerDatabase(config.ConnectionDetails)
+		newMigrator = sqlserver.NewMigrator
+
+	case DriverSQLite:
+		sqldb, err = sqlite.Open(config.Path)
+		newMigrator = sqlite.NewMigrator
+
+	default:
+		return nil, nil, fmt.Errorf("database driver %q has no schema", config.Driver)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := newMigrator(sqldb)
+	if err != nil {
+		sqldb.Close()
+		return nil, nil, err
+	}
+
+	return sqldb, migrator, nil
+}
+
 func NewSQLServerDatabase(details ConnectionDetails) (*sql.DB, error) {
 	query := url.Values{}
 	query.Add("app name", "Nameless")
@@ -1,16 +1,88 @@
 package main
 
-import "github.com/Edwing123/udem-chat-app/pkg/codes"
+import (
+	"errors"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
+	"github.com/gofiber/fiber/v2"
+)
 
 var (
 	// Server related.
-	ErrServerInternal = codes.NewCode("server_internal")
+	ErrServerInternal     = codes.NewCodeWithStatus("server_internal", fiber.StatusInternalServerError)
+	ErrServiceUnavailable = codes.NewCodeWithStatus("service_unavailable", fiber.StatusServiceUnavailable)
 
 	// Client related.
-	ErrCannotDecodeJSON   = codes.NewCode(("cannot_decode_json"))
-	ErrAuthRequired       = codes.NewCode("auth_required")
+	ErrCannotDecodeJSON   = codes.NewCode("cannot_decode_json")
+	ErrAuthRequired       = codes.NewCodeWithStatus("auth_required", fiber.StatusUnauthorized)
 	ErrProfileImageTooBig = codes.NewCode("profile_image_too_big")
+	ErrSessionNotFound    = codes.NewCodeWithStatus("session_not_found", fiber.StatusNotFound)
+	ErrResourceNotFound   = codes.NewCodeWithStatus("resource_not_found", fiber.StatusNotFound)
+	ErrRequestNotValid    = codes.NewCode("request_not_valid")
+
+	// Localization related.
+	ErrLanguageNotSupported = codes.NewCode("language_not_supported")
 
 	// Validation related.
 	ErrPasswordNotValid = codes.NewCode("password_not_valid")
+	ErrValidationFailed = codes.NewCode("validation_failed")
 )
+
+// Turns the errors returned by the handlers into the `ErrorMessage`
+// envelope, the status comes from the code of the error (see
+// `codes.Code.Status`) and the details are the default message of
+// the code in the language of the user (see `codes.WithParams` for
+// the parameters of the message), unless the error carries its own
+// details (see `codes.WithDetails`).
+//
+// The errors without a code and the server errors are logged and
+// sent as `ErrServerInternal`, so they never reach the client,
+// except the unavailable services, for example while shutting down.
+func (g *Global) ErrorHandler(c *fiber.Ctx, err error) error {
+	var code codes.Code
+	var fiberErr *fiber.Error
+
+	status := fiber.StatusInternalServerError
+
+	switch {
+	case errors.As(err, &code):
+		status = code.Status()
+
+	// Returned by Fiber itself, for example for unknown routes.
+	case errors.As(err, &fiberErr):
+		code = ErrRequestNotValid
+		status = fiberErr.Code
+
+		switch status {
+		case fiber.StatusNotFound:
+			code = ErrResourceNotFound
+		case fiber.StatusServiceUnavailable:
+			code = ErrServiceUnavailable
+		}
+	}
+
+	if status >= fiber.StatusInternalServerError && status != fiber.StatusServiceUnavailable {
+		g.Logger.Error("Request failed", err, "method", c.Method(), "path", c.Path())
+
+		code = ErrServerInternal
+		status = fiber.StatusInternalServerError
+		err = code
+	}
+
+	var params i18n.Params
+
+	var withParams *codes.ParamsError
+	if errors.As(err, &withParams) {
+		params = withParams.Params
+	}
+
+	var details any = g.Messages.Error(g.Language(c), code, params)
+
+	var detailed *codes.DetailedError
+	if errors.As(err, &detailed) {
+		details = detailed.Details
+	}
+
+	return SendErrorMessage(c, status, code, details)
+}
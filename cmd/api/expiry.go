@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"golang.org/x/exp/slog"
+)
+
+// ExpiryScheduler ends the conversations once
+// their time limit (`Conversation.Duration`) elapses.
+type ExpiryScheduler struct {
+	mu sync.Mutex
+
+	// Timers of the scheduled conversations by conversation id.
+	timers map[int]*time.Timer
+
+	// Counts the scheduled ends, so `ExpiryScheduler.Stop`
+	// can wait for the ones already running.
+	pending sync.WaitGroup
+
+	conversations models.ConversationManager
+	hub           *realtime.Hub
+	logger        *slog.Logger
+}
+
+// Creates a scheduler that ends the conversations using the
+// provided conversation manager and notifies the participants
+// through the provided hub.
+func NewExpiryScheduler(
+	conversations models.ConversationManager,
+	hub *realtime.Hub,
+	logger *slog.Logger,
+) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		timers:        map[int]*time.Timer{},
+		conversations: conversations,
+		hub:           hub,
+		logger:        logger,
+	}
+}
+
+// Schedules the end of the conversation, conversations without
+// a time limit, already ended or already scheduled are ignored.
+func (s *ExpiryScheduler) Schedule(conversation models.Conversation) {
+	expiresAt, ok := conversation.ExpiresAt()
+	if !ok || conversation.EndedAt != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timers == nil {
+		return
+	}
+
+	if _, ok := s.timers[conversation.Id]; ok {
+		return
+	}
+
+	id := conversation.Id
+
+	s.pending.Add(1)
+	s.timers[id] = time.AfterFunc(time.Until(expiresAt), func() {
+		defer s.pending.Done()
+		s.expire(id)
+	})
+}
+
+// Schedules every active conversation that has a time limit, it's
+// meant to be called on startup, the conversations that expired
+// while the server was down are ended right away.
+func (s *ExpiryScheduler) Reschedule() error {
+	conversations, err := s.conversations.ListExpiring()
+	if err != nil {
+		return err
+	}
+
+	for _, conversation := range conversations {
+		s.Schedule(conversation)
+	}
+
+	s.logger.Info("conversations expiry rescheduled", "count", len(conversations))
+
+	return nil
+}
+
+// Cancels the scheduled end of the conversation
+// identified by id, for example when it's ended early.
+func (s *ExpiryScheduler) Cancel(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, ok := s.timers[id]
+	if !ok {
+		return
+	}
+
+	if timer.Stop() {
+		s.pending.Done()
+	}
+
+	delete(s.timers, id)
+}
+
+// Cancels every scheduled end and waits for the ones
+// already running, the scheduler can not be used afterwards.
+func (s *ExpiryScheduler) Stop() {
+	s.mu.Lock()
+
+	for _, timer := range s.timers {
+		if timer.Stop() {
+			s.pending.Done()
+		}
+	}
+
+	s.timers = nil
+
+	s.mu.Unlock()
+
+	s.pending.Wait()
+}
+
+// Ends the conversation identified by id and
+// notifies its participants.
+func (s *ExpiryScheduler) expire(id int) {
+	s.mu.Lock()
+	delete(s.timers, id)
+	s.mu.Unlock()
+
+	err := s.conversations.End(id)
+	if err != nil {
+		// The conversation could've been ended by its participants.
+		if !errors.Is(err, models.ErrConversationEnded) {
+			s.logger.Error("Expire conversation", err, "conversationId", id)
+		}
+
+		return
+	}
+
+	conversation, err := s.conversations.Get(id)
+	if err != nil {
+		s.logger.Error("Expire conversation - get participants", err, "conversationId", id)
+		return
+	}
+
+	s.hub.Publish(conversation.Participants, realtime.Event{
+		Type: realtime.EventConversationEnded,
+		Data: realtime.ConversationEvent{
+			ConversationId: id,
+			Reason:         "expired",
+		},
+	})
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/models/memory"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"golang.org/x/exp/slog"
+)
+
+// Wraps the in-memory conversation manager, so the tests can
+// pretend the server was down and hold the ends of the conversations.
+type testConversations struct {
+	models.ConversationManager
+
+	// Time added to the age of the listed conversations,
+	// as if the server had been down that long.
+	downtime time.Duration
+
+	// When not nil, `End` signals entered and waits
+	// for release before ending the conversation.
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (tc *testConversations) ListExpiring() ([]models.Conversation, error) {
+	conversations, err := tc.ConversationManager.ListExpiring()
+
+	for i := range conversations {
+		conversations[i].CreatedAt = conversations[i].CreatedAt.Add(-tc.downtime)
+	}
+
+	return conversations, err
+}
+
+func (tc *testConversations) End(id int) error {
+	if tc.entered != nil {
+		tc.entered <- struct{}{}
+		<-tc.release
+	}
+
+	return tc.ConversationManager.End(id)
+}
+
+// Creates the conversation manager backed by the
+// in-memory database along with two of its users.
+func newTestConversations(t *testing.T) (*testConversations, []int) {
+	t.Helper()
+
+	database := memory.New(hashing.Default(), slog.New(slog.NewTextHandler(io.Discard)))
+
+	ids := []int{}
+
+	for _, name := range []string{"edwin", "carlos"} {
+		user := models.User{
+			Name:      name,
+			Password:  "password#123",
+			Birthdate: "2000-01-01",
+		}
+
+		err := database.UserManager.New(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, err := database.UserManager.Login(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return &testConversations{ConversationManager: database.ConversationManager}, ids
+}
+
+func newTestScheduler(conversations models.ConversationManager, hub *realtime.Hub) *ExpiryScheduler {
+	return NewExpiryScheduler(conversations, hub, slog.New(slog.NewTextHandler(io.Discard)))
+}
+
+// Returns the conversation as if it had been created duration ago.
+func createdAgo(conversation models.Conversation, duration time.Duration) models.Conversation {
+	conversation.CreatedAt = conversation.CreatedAt.Add(-duration)
+	return conversation
+}
+
+// Connects to the hub as the user identified by userId, it
+// returns once the connection receives the events.
+func connectHub(t *testing.T, hub *realtime.Hub, userId int) *websocket.Conn {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		return hub.Upgrade(c, userId, "")
+	})
+
+	listener := fasthttputil.NewInmemoryListener()
+
+	go app.Listener(listener)
+
+	t.Cleanup(func() {
+		app.Shutdown()
+	})
+
+	dialer := websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return listener.Dial()
+		},
+	}
+
+	conn, _, err := dialer.Dial("ws://hub/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	// The connection is registered after the handshake,
+	// so publish until the first event gets through.
+	registered := make(chan struct{})
+	probing := make(chan struct{})
+
+	go func() {
+		defer close(probing)
+
+		for {
+			select {
+			case <-registered:
+				return
+			case <-time.After(5 * time.Millisecond):
+				hub.Publish([]int{userId}, realtime.Event{Type: "probe"})
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	_, _, err = conn.ReadMessage()
+	close(registered)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-probing
+
+	return conn
+}
+
+// Reads the next event that isn't a probe, see `connectHub`.
+func readHubEvent(t *testing.T, conn *websocket.Conn) realtime.Event {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	for {
+		var event struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+
+		err := conn.ReadJSON(&event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if event.Type == "probe" {
+			continue
+		}
+
+		var data realtime.ConversationEvent
+		json.Unmarshal(event.Data, &data)
+
+		return realtime.Event{Type: event.Type, Data: data}
+	}
+}
+
+// Waits for the conversation identified by id to end.
+func waitEnded(t *testing.T, conversations models.ConversationManager, id int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		conversation, err := conversations.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if conversation.EndedAt != nil {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected conversation %d to end", id)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExpirySchedulerExpire(t *testing.T) {
+	conversations, ids := newTestConversations(t)
+	hub := realtime.New(slog.New(slog.NewTextHandler(io.Discard)))
+
+	s := newTestScheduler(conversations, hub)
+	t.Cleanup(s.Stop)
+
+	conn := connectHub(t, hub, ids[1])
+
+	conversation, err := conversations.New(60, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Schedule(createdAgo(conversation, time.Minute))
+
+	event := readHubEvent(t, conn)
+	if event.Type != realtime.EventConversationEnded {
+		t.Fatalf("expected event %q, got %q", realtime.EventConversationEnded, event.Type)
+	}
+
+	expected := realtime.ConversationEvent{ConversationId: conversation.Id, Reason: "expired"}
+	if event.Data != expected {
+		t.Errorf("expected data %+v, got %+v", expected, event.Data)
+	}
+
+	waitEnded(t, conversations, conversation.Id)
+}
+
+func TestExpirySchedulerStop(t *testing.T) {
+	conversations, ids := newTestConversations(t)
+	conversations.entered = make(chan struct{})
+	conversations.release = make(chan struct{})
+
+	s := newTestScheduler(conversations, realtime.New(slog.New(slog.NewTextHandler(io.Discard))))
+
+	conversation, err := conversations.New(60, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Schedule(createdAgo(conversation, time.Minute))
+
+	// The end of the conversation is running.
+	select {
+	case <-conversations.entered:
+	case <-time.After(time.Second):
+		t.Fatal("expected the conversation to be ending")
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("expected Stop to wait for the running end")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(conversations.release)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected Stop to return")
+	}
+
+	// Stop returned once the conversation ended.
+	ended, err := conversations.Get(conversation.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ended.EndedAt == nil {
+		t.Error("expected the conversation to be ended")
+	}
+
+	// Nothing is scheduled once it's stopped.
+	other, err := conversations.New(60, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Schedule(createdAgo(other, time.Minute))
+	time.Sleep(20 * time.Millisecond)
+
+	other, err = conversations.Get(other.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.EndedAt != nil {
+		t.Error("expected the conversation not to be ended")
+	}
+}
+
+func TestExpirySchedulerReschedule(t *testing.T) {
+	conversations, ids := newTestConversations(t)
+	conversations.downtime = 2 * time.Minute
+
+	s := newTestScheduler(conversations, realtime.New(slog.New(slog.NewTextHandler(io.Discard))))
+	t.Cleanup(s.Stop)
+
+	// Expired while the server was down.
+	expired, err := conversations.New(60, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := conversations.New(3600, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlimited, err := conversations.New(0, ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Reschedule()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	waitEnded(t, conversations, expired.Id)
+
+	for _, id := range []int{active.Id, unlimited.Id} {
+		conversation, err := conversations.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if conversation.EndedAt != nil {
+			t.Errorf("expected conversation %d not to be ended", id)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.timers[active.Id]; !ok {
+		t.Errorf("expected conversation %d to be scheduled", active.Id)
+	}
+}
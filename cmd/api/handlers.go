@@ -4,32 +4,91 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
 
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
 	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
+	"github.com/Edwing123/udem-chat-app/pkg/lockout"
 	"github.com/Edwing123/udem-chat-app/pkg/models"
-	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/validator"
 	"github.com/gofiber/fiber/v2"
 	"github.com/h2non/bimg"
 )
 
-// Handler for authenticating user.
-func (g *Global) UserLogIn(c *fiber.Ctx) error {
-	credentials, err := ReadBodyFromRequest[models.User](c)
+// Authenticates the credentials applying the brute-force protection,
+// the failed attempts are counted by user name and by IP address.
+//
+// A locked login fails with the details `retryAfter`, which is
+// sent in the header `Retry-After` as well.
+func (g *Global) login(c *fiber.Ctx, credentials models.User) (int, error) {
+	userKey := lockout.UserKey(credentials.Name)
+	keys := []string{userKey, lockout.IPKey(c.IP())}
+
+	err := g.Lockout.Check(keys...)
 	if err != nil {
-		return SendErrorMessage(c, fiber.StatusBadRequest, ErrCannotDecodeJSON, err)
+		var locked *lockout.LockedError
+		if errors.As(err, &locked) {
+			retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+
+			return 0, codes.WithDetails(lockout.ErrLocked, fiber.Map{
+				"retryAfter": retryAfter,
+			})
+		}
+
+		return 0, fmt.Errorf("check login lockout: %w", err)
 	}
 
 	id, err := g.Database.UserManager.Login(credentials)
 	if err != nil {
 		if errors.Is(err, models.ErrLoginFail) {
-			return SendErrorMessage(c, fiber.StatusUnauthorized, err, "")
+			failErr := g.Lockout.Fail(keys...)
+			if failErr != nil {
+				g.Logger.Error("Login - count failure", failErr)
+			}
+		} else {
+			settleErr := g.Lockout.Settle(keys...)
+			if settleErr != nil {
+				g.Logger.Error("Login - settle attempt", settleErr)
+			}
 		}
 
-		if errors.Is(err, models.ErrDatabaseServerFail) {
-			return g.ServerError(c, nil)
-		}
+		return 0, err
+	}
+
+	err = g.Lockout.Settle(keys...)
+	if err != nil {
+		g.Logger.Error("Login - settle attempt", err)
+	}
+
+	// The counter of the IP is kept, otherwise an attacker could
+	// reset it by logging in with its own account from time to time.
+	err = g.Lockout.Reset(userKey)
+	if err != nil {
+		g.Logger.Error("Login - reset failures", err)
+	}
+
+	return id, nil
+}
+
+// Handler for authenticating user.
+func (g *Global) UserLogIn(c *fiber.Ctx) error {
+	credentials, err := ReadBodyFromRequest[models.User](c)
+	if err != nil {
+		return err
+	}
+
+	v := models.CheckLogin(credentials)
+	if !v.Valid() {
+		return ValidationError(v)
+	}
 
-		return SendErrorMessage(c, fiber.StatusBadRequest, err, "")
+	id, err := g.login(c, credentials)
+	if err != nil {
+		return err
 	}
 
 	// Save user id and status inside its session.
@@ -37,6 +96,18 @@ func (g *Global) UserLogIn(c *fiber.Ctx) error {
 	sess.Set(UserIdKey, id)
 	sess.Set(IsLoggedInKey, true)
 
+	// Save the information shown in the sessions list.
+	now := time.Now().Unix()
+	sess.Set(SessionDeviceKey, c.Get(fiber.HeaderUserAgent))
+	sess.Set(SessionIPKey, c.IP())
+	sess.Set(SessionCreatedAtKey, now)
+	sess.Set(SessionLastSeenAtKey, now)
+
+	err = g.Sessions.Add(id, sess.ID())
+	if err != nil {
+		return fmt.Errorf("add user session: %w", err)
+	}
+
 	return SendSucessMessage(c, fiber.StatusOK, fiber.Map{
 		"id": id,
 	})
@@ -44,52 +115,229 @@ func (g *Global) UserLogIn(c *fiber.Ctx) error {
 
 // Handler for logging out user.
 func (g *Global) UserLogout(c *fiber.Ctx) error {
+	// Users authenticated with a bearer token
+	// log out by revoking their refresh token.
+	if tokenId := g.GetTokenId(c); tokenId != "" {
+		err := g.Tokens.RevokeId(g.GetUserId(c), tokenId)
+		if err != nil {
+			return fmt.Errorf("revoke user token: %w", err)
+		}
+
+		return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "session.closed", nil))
+	}
+
 	sess := g.GetSession(c)
 
-	err := sess.Destroy()
+	err := g.Sessions.Remove(g.GetUserId(c), sess.ID())
 	if err != nil {
-		return g.ServerError(c, err)
+		return fmt.Errorf("remove user session: %w", err)
 	}
 
-	return SendSucessMessage(c, fiber.StatusOK, "Sesion cerrada")
+	err = sess.Destroy()
+	if err != nil {
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "session.closed", nil))
 }
 
 // Handler for signing up a new user.
 func (g *Global) UserSignUp(c *fiber.Ctx) error {
 	user, err := ReadBodyFromRequest[models.User](c)
 	if err != nil {
-		return SendErrorMessage(c, fiber.StatusBadRequest, ErrCannotDecodeJSON, err.Error())
+		return err
 	}
 
-	err = ValidatePassword(user.Password)
-	if err != nil {
-		return SendErrorMessage(
-			c,
-			fiber.StatusBadRequest,
-			ErrPasswordNotValid,
-			"Contraseña no cumple las reglas de validacion",
-		)
+	v := models.CheckNewUser(user)
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	rules := g.PasswordPolicy.Validate(user.Password, user.Name)
+	if len(rules) > 0 {
+		return g.PasswordError(c, rules)
 	}
 
 	err = g.Database.UserManager.New(user)
 	if err != nil {
 		if errors.Is(err, models.ErrUserNameExists) {
-			return SendErrorMessage(
-				c,
-				fiber.StatusConflict,
-				err,
-				fmt.Sprintf("Usuario %s ya existe", user.Name),
-			)
+			return codes.WithParams(models.ErrUserNameExists, i18n.Params{"name": user.Name})
 		}
 
-		if errors.Is(err, models.ErrDatabaseServerFail) || errors.Is(err, hashing.ErrPasswordHashingFail) {
-			return g.ServerError(c, nil)
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusCreated, g.Message(c, "user.signed_up", i18n.Params{"name": user.Name}))
+}
+
+// Handler for changing the password of the logged-in user, the other
+// sessions and connections of the user are closed once the password changes.
+func (g *Global) UserChangePassword(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[ChangePasswordRequest](c)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	v.Check(request.CurrentPassword != "", "currentPassword", models.ErrUserPasswordEmpty)
+	v.Check(request.NewPassword != "", "newPassword", models.ErrUserPasswordEmpty)
+
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	id := g.GetUserId(c)
+
+	user, err := g.Database.UserManager.Get(id)
+	if err != nil {
+		return err
+	}
+
+	rules := g.PasswordPolicy.Validate(request.NewPassword, user.Name)
+	if len(rules) > 0 {
+		return g.PasswordError(c, rules)
+	}
+
+	err = g.Database.UserManager.ChangePassword(id, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = g.Sessions.RevokeAll(id, g.GetSession(c).ID())
+	if err != nil {
+		return fmt.Errorf("revoke user sessions: %w", err)
+	}
+
+	err = g.Tokens.RevokeAll(id, g.GetTokenId(c))
+	if err != nil {
+		return fmt.Errorf("revoke user tokens: %w", err)
+	}
+
+	g.Hub.Disconnect(id, g.GetLoginId(c))
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "user.password_changed", nil))
+}
+
+// Handler for deleting the account of the logged-in user, the data
+// of the user is handled according to the configured deletion policy
+// and every session and connection of the user is closed.
+func (g *Global) UserDelete(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[DeleteUserRequest](c)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+	v.Check(request.Password != "", "password", models.ErrUserPasswordEmpty)
+
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	id := g.GetUserId(c)
+
+	imageId, err := g.Database.UserManager.Delete(id, request.Password, g.DeletionPolicy)
+	if err != nil {
+		return err
+	}
+
+	// The account is gone at this point, so the failures
+	// below are logged instead of failing the request.
+	_ = g.MatchQueue.Cancel(id)
+
+	if imageId != "" {
+		err = g.ProfileManager.Archive(imageId)
+		if err != nil {
+			g.Logger.Error("Delete user - archive profile picture", err, "userId", id)
 		}
+	}
+
+	sess := g.GetSession(c)
+
+	err = g.Sessions.RevokeAll(id, "")
+	if err != nil {
+		g.Logger.Error("Delete user - revoke sessions", err, "userId", id)
+	}
+
+	err = g.Tokens.RevokeAll(id, "")
+	if err != nil {
+		g.Logger.Error("Delete user - revoke tokens", err, "userId", id)
+	}
+
+	g.Hub.Disconnect(id, "")
+
+	err = sess.Destroy()
+	if err != nil {
+		g.Logger.Error("Delete user - destroy session", err, "userId", id)
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "user.deleted", nil))
+}
+
+// Handler for listing the active sessions of the logged-in user.
+func (g *Global) UserSessionList(c *fiber.Ctx) error {
+	sessions, err := g.Sessions.List(g.GetUserId(c))
+	if err != nil {
+		return fmt.Errorf("list user sessions: %w", err)
+	}
+
+	current := PublicSessionId(g.GetSession(c).ID())
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == current
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, sessions)
+}
+
+// Handler for closing one of the sessions of the logged-in user along
+// with its connections, the session is identified by the route parameter `id`.
+func (g *Global) UserSessionRevoke(c *fiber.Ctx) error {
+	sess := g.GetSession(c)
+
+	userId := g.GetUserId(c)
+
+	id, err := g.Sessions.Revoke(userId, c.Params("id"))
+	if err != nil {
+		return fmt.Errorf("revoke user session: %w", err)
+	}
+
+	g.Hub.DisconnectSession(userId, id)
+
+	// Otherwise the session would be saved again after the request.
+	if id == sess.ID() {
+		err = sess.Destroy()
+		if err != nil {
+			return err
+		}
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "session.closed", nil))
+}
+
+// Handler for closing every session and connection of the logged-in user,
+// including the current one, the refresh tokens of the user are revoked as well.
+func (g *Global) UserSessionRevokeAll(c *fiber.Ctx) error {
+	id := g.GetUserId(c)
+
+	err := g.Sessions.RevokeAll(id, "")
+	if err != nil {
+		return fmt.Errorf("revoke user sessions: %w", err)
+	}
 
-		return SendErrorMessage(c, fiber.StatusBadRequest, err, "")
+	err = g.Tokens.RevokeAll(id, "")
+	if err != nil {
+		return fmt.Errorf("revoke user tokens: %w", err)
+	}
+
+	g.Hub.Disconnect(id, "")
+
+	err = g.GetSession(c).Destroy()
+	if err != nil {
+		return err
 	}
 
-	return SendSucessMessage(c, fiber.StatusCreated, "Usuario registrado")
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "sessions.closed", nil))
 }
 
 // Handler for getting the status of the user.
@@ -103,8 +351,36 @@ func (g *Global) UserStatus(c *fiber.Ctx) error {
 	})
 }
 
+// Handler for choosing the language of the messages, the choice
+// is kept in the session, so it works for anonymous users as well.
+func (g *Global) UserLanguage(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[LanguageRequest](c)
+	if err != nil {
+		return err
+	}
+
+	if !g.Messages.Has(request.Language) {
+		return codes.WithDetails(ErrLanguageNotSupported, g.Messages.Languages())
+	}
+
+	g.GetSession(c).Set(LanguageKey, request.Language)
+	c.Locals(LanguageKey, request.Language)
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "user.language_changed", nil))
+}
+
 // Handler for updating the user information.
 func (g *Global) UserUpdate(c *fiber.Ctx) error {
+	// Get the other values from the request body.
+	userName := c.FormValue("name", "")
+	birthdate := c.FormValue("birthdate", "")
+
+	// Check them before processing the image.
+	v := models.CheckUserUpdate(models.User{Name: userName, Birthdate: birthdate})
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
 	var updateImage bool
 
 	imageFile, err := c.FormFile("profilePicture")
@@ -116,15 +392,8 @@ func (g *Global) UserUpdate(c *fiber.Ctx) error {
 
 	// Update the image only if required.
 	if updateImage {
-		const maxImageSize = 1024 * 1024 * 1.5 // 1.5MB
-
-		if imageFile.Size > maxImageSize {
-			return SendErrorMessage(
-				c,
-				fiber.StatusBadRequest,
-				ErrProfileImageTooBig,
-				"El tamaño de la imagen es muy grande",
-			)
+		if imageFile.Size > g.Settings.Load().MaxImageSize {
+			return ErrProfileImageTooBig
 		}
 
 		// Find the corresponding type of the image based on the `bimg` package.
@@ -146,27 +415,19 @@ func (g *Global) UserUpdate(c *fiber.Ctx) error {
 		cropJSONString := c.FormValue("crop", "")
 		crop, err := ReadJSONBody[profile.Crop]([]byte(cropJSONString))
 		if err != nil {
-			return SendErrorMessage(c, fiber.StatusBadRequest, ErrCannotDecodeJSON, err.Error())
+			return err
 		}
 
 		// Read the image data.
 		imageFile, err := imageFile.Open()
 		if err != nil {
-			g.Logger.Error("Open profile picture", err)
-			return g.ServerError(
-				c,
-				nil,
-			)
+			return fmt.Errorf("open profile picture: %w", err)
 		}
 		defer imageFile.Close()
 
 		imageBuffer, err := ioutil.ReadAll(imageFile)
 		if err != nil {
-			g.Logger.Error("Read profile picture", err)
-			return g.ServerError(
-				c,
-				nil,
-			)
+			return fmt.Errorf("read profile picture: %w", err)
 		}
 
 		imageId, err = g.ProfileManager.New(profile.Image{
@@ -175,38 +436,11 @@ func (g *Global) UserUpdate(c *fiber.Ctx) error {
 		}, crop)
 
 		if err != nil {
-			if errors.Is(err, profile.ErrImageTypeNotSupported) {
-				return SendErrorMessage(
-					c,
-					fiber.StatusBadRequest,
-					err,
-					fmt.Sprintf("El tipo de archivo %s no es soportado", imageFileType),
-				)
-			}
-
-			if errors.Is(err, profile.ErrCannotGetImageSize) {
-				return SendErrorMessage(
-					c,
-					fiber.StatusBadRequest,
-					err,
-					"La imagen no puede ser procesada, elija otra porfavor",
-				)
-			}
-
-			g.Logger.Error("New profile image", err)
-			return g.ServerError(c, nil)
+			return err
 		}
 	}
 
-	// Get user id from session.
-	sess := g.GetSession(c)
-
-	// TODO: handle panic when the casting fails.
-	id := sess.Get(UserIdKey).(int)
-
-	// Get the other values from the request body.
-	userName := c.FormValue("name", "")
-	birthdate := c.FormValue("birthdate", "")
+	id := g.GetUserId(c)
 
 	updatedUser, oldImageId, err := g.Database.UserManager.Update(id, models.User{
 		Name:             userName,
@@ -214,36 +448,17 @@ func (g *Global) UserUpdate(c *fiber.Ctx) error {
 		ProfilePictureId: imageId,
 	})
 	if err != nil {
-		if errors.Is(err, models.ErrNoUpdates) {
-			return SendErrorMessage(
-				c,
-				fiber.StatusBadRequest,
-				err,
-				"No hay nada que actualizar :|",
-			)
-		}
-
 		if errors.Is(err, models.ErrUserNameExists) {
-			return SendErrorMessage(
-				c,
-				fiber.StatusConflict,
-				err,
-				fmt.Sprintf("El nombre de usuario %s ya existe", userName),
-			)
-		}
-
-		if errors.Is(err, models.ErrDatabaseServerFail) {
-			g.Logger.Error("Update user", err)
-			return g.ServerError(c, nil)
+			return codes.WithParams(models.ErrUserNameExists, i18n.Params{"name": userName})
 		}
 
-		return SendErrorMessage(c, fiber.StatusBadRequest, err, "")
+		return err
 	}
 
 	if oldImageId != "" {
 		err = g.ProfileManager.Archive(oldImageId)
 		if err != nil {
-			return g.ServerError(c, nil)
+			return err
 		}
 	}
 
@@ -253,12 +468,11 @@ func (g *Global) UserUpdate(c *fiber.Ctx) error {
 // Handler for getting the information
 // of the logged-in user.
 func (g *Global) UserGet(c *fiber.Ctx) error {
-	sess := g.GetSession(c)
-	id := sess.Get(UserIdKey).(int)
+	id := g.GetUserId(c)
 
 	user, err := g.Database.UserManager.Get(id)
 	if err != nil {
-		return g.ServerError(c, nil)
+		return err
 	}
 
 	return SendSucessMessage(c, fiber.StatusOK, user)
@@ -0,0 +1,1165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/health"
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
+	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
+	"github.com/Edwing123/udem-chat-app/pkg/lockout"
+	"github.com/Edwing123/udem-chat-app/pkg/matchmaking"
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/models/memory"
+	"github.com/Edwing123/udem-chat-app/pkg/ratelimit"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/Edwing123/udem-chat-app/pkg/tokens"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/password"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/validator"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// Path of the checked-in OpenAPI document, relative to the package.
+const openAPIPath = "../../docs/openapi.json"
+
+var updateOpenAPI = flag.Bool("update", false, "write the generated OpenAPI document to "+openAPIPath)
+
+// Creates the app backed by the in-memory database
+// and an in-memory sessions storage.
+func newTestApp(t *testing.T, policy models.DeletionPolicy) (*fiber.App, *Global) {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard))
+	metrics := NewMetrics()
+
+	hasher := hashing.Default()
+	hasher.Observe(metrics.ObservePasswordHash)
+
+	database := memory.New(hasher, logger)
+	profileManager := profile.New(t.TempDir(), logger)
+	profileManager.Observe(metrics.ObserveImageStage)
+
+	store := NewSessionStore(nil)
+	store.Storage = metrics.InstrumentStorage(store.Storage)
+
+	tokenManager, err := NewTokenManager(DefaultConfig(), store.Storage, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := i18n.New(i18n.DefaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The results are not cached, so the tests see the changes,
+	// and the disk check doesn't depend on the machine.
+	healthConfig := DefaultConfig()
+	healthConfig.AppData = t.TempDir()
+	healthConfig.Health.CacheTTL = 0
+	healthConfig.Health.MinFreeDisk = 1
+
+	err = profileManager.InitDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := realtime.New(logger)
+
+	expiry := NewExpiryScheduler(database.ConversationManager, hub, logger)
+	t.Cleanup(expiry.Stop)
+
+	g := &Global{
+		Logger:         logger,
+		Store:          store,
+		Sessions:       NewSessionIndex(store.Storage),
+		Tokens:         tokenManager,
+		Lockout:        NewLoginLimiter(DefaultConfig(), store.Storage),
+		RateLimiter:    ratelimit.New(store.Storage, logger),
+		ProfileManager: &profileManager,
+		Database:       &database,
+		Hub:            hub,
+		MatchQueue: matchmaking.New(database.ConversationManager, matchmaking.Options{
+			Duration: 300,
+			Timeout:  time.Minute,
+		}, logger),
+		Expiry:         expiry,
+		Messages:       messages,
+		Health:         NewHealthChecker(healthConfig, store.Storage, nil, &profileManager),
+		Metrics:        metrics,
+		DeletionPolicy: policy,
+		PasswordPolicy: NewPasswordPolicy(DefaultConfig()),
+		LogLevel:       new(slog.LevelVar),
+	}
+
+	g.ApplySettings(DefaultConfig())
+
+	return g.Setup(DefaultConfig()), g
+}
+
+// Sends a request with a JSON body (if not nil) and the
+// cookies of the client, the cookies set by the response
+// are saved back into the client.
+func doRequest(t *testing.T, app *fiber.App, cookies map[string]string, method, path string, body any) *http.Response {
+	t.Helper()
+
+	return doRequestWithHeaders(t, app, cookies, nil, method, path, body)
+}
+
+// Same as `doRequest` but it sends the provided headers as well.
+func doRequestWithHeaders(
+	t *testing.T,
+	app *fiber.App,
+	cookies map[string]string,
+	headers map[string]string,
+	method, path string,
+	body any,
+) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cookie := range res.Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+
+	return res
+}
+
+// Creates the user and logs it in, returning
+// its id and the cookies of its session.
+func newLoggedInUser(t *testing.T, app *fiber.App, g *Global, name string) (int, map[string]string) {
+	t.Helper()
+
+	user := models.User{
+		Name:      name,
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := g.Database.UserManager.Login(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := map[string]string{}
+
+	res := doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("login: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	return id, cookies
+}
+
+func TestUserChangePassword(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two clients logged in as the same user.
+	current := map[string]string{}
+	other := map[string]string{}
+
+	for _, cookies := range []map[string]string{current, other} {
+		res := doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("login: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		request ChangePasswordRequest
+		status  int
+	}{
+		{
+			name:    "wrong current password",
+			request: ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "password#456"},
+			status:  fiber.StatusUnauthorized,
+		},
+		{
+			name:    "new password not valid",
+			request: ChangePasswordRequest{CurrentPassword: user.Password, NewPassword: "short"},
+			status:  fiber.StatusBadRequest,
+		},
+		{
+			name:    "valid change",
+			request: ChangePasswordRequest{CurrentPassword: user.Password, NewPassword: "password#456"},
+			status:  fiber.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		res := doRequest(t, app, current, fiber.MethodPost, "/api/user/password", test.request)
+		if res.StatusCode != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, res.StatusCode)
+		}
+	}
+
+	// The other session is closed, the current one is kept.
+	res := doRequest(t, app, other, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("other session: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	res = doRequest(t, app, current, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("current session: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	// Only the new password works.
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", user)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("old password: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	user.Password = "password#456"
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", user)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("new password: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+}
+
+// The sessions in use outlive their expiration, so must their index.
+func TestUserSessionsExpiration(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	config := DefaultConfig()
+	config.Session.Expiration = 2
+	g.ApplySettings(config)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := map[string]string{}
+	other := map[string]string{}
+
+	for _, cookies := range []map[string]string{current, other} {
+		res := doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("login: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+		}
+	}
+
+	// Both sessions are used past their expiration.
+	for end := time.Now().Add(3 * time.Second); time.Now().Before(end); {
+		for _, cookies := range []map[string]string{current, other} {
+			res := doRequest(t, app, cookies, fiber.MethodGet, "/api/user/data", nil)
+			if res.StatusCode != fiber.StatusOK {
+				t.Fatalf("data: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+			}
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+
+	res := doRequest(t, app, current, fiber.MethodPost, "/api/user/password", ChangePasswordRequest{
+		CurrentPassword: user.Password,
+		NewPassword:     "password#456",
+	})
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("change password: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, other, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("other session: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	for _, policy := range []models.DeletionPolicy{models.DeletionPolicyDelete, models.DeletionPolicyTombstone} {
+		app, g := newTestApp(t, policy)
+
+		user := models.User{
+			Name:      "edwin",
+			Password:  "password#123",
+			Birthdate: "2000-01-01",
+		}
+		partner := models.User{
+			Name:      "partner",
+			Password:  "password#123",
+			Birthdate: "2000-01-01",
+		}
+
+		for _, u := range []models.User{user, partner} {
+			err := g.Database.UserManager.New(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		current := map[string]string{}
+		other := map[string]string{}
+
+		for _, cookies := range []map[string]string{current, other} {
+			res := doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+			if res.StatusCode != fiber.StatusOK {
+				t.Fatalf("%s: login: expected status %d, got %d", policy, fiber.StatusOK, res.StatusCode)
+			}
+		}
+
+		id, err := g.Database.UserManager.Login(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		partnerId, err := g.Database.UserManager.Login(partner)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conversation, err := g.Database.ConversationManager.New(0, []int{id, partnerId}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = g.Database.MessageManager.New(models.Message{
+			Content:        "hola",
+			UserId:         id,
+			ConversationId: conversation.Id,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := doRequest(t, app, current, fiber.MethodDelete, "/api/user", DeleteUserRequest{Password: "wrong"})
+		if res.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("%s: wrong password: expected status %d, got %d", policy, fiber.StatusUnauthorized, res.StatusCode)
+		}
+
+		res = doRequest(t, app, current, fiber.MethodDelete, "/api/user", DeleteUserRequest{Password: user.Password})
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", policy, fiber.StatusOK, res.StatusCode)
+		}
+
+		// Every session of the user is closed.
+		for _, cookies := range []map[string]string{current, other} {
+			res := doRequest(t, app, cookies, fiber.MethodGet, "/api/user/data", nil)
+			if res.StatusCode != fiber.StatusUnauthorized {
+				t.Errorf("%s: session: expected status %d, got %d", policy, fiber.StatusUnauthorized, res.StatusCode)
+			}
+		}
+
+		res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", user)
+		if res.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("%s: login: expected status %d, got %d", policy, fiber.StatusUnauthorized, res.StatusCode)
+		}
+
+		conversation, err = g.Database.ConversationManager.Get(conversation.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := g.Database.MessageManager.History(conversation.Id, models.HistoryQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the tombstone keeps the data of the user.
+		kept := policy == models.DeletionPolicyTombstone
+
+		if conversation.HasParticipant(id) != kept {
+			t.Errorf("%s: expected participant kept to be %t", policy, kept)
+		}
+
+		if (len(page.Messages) == 1) != kept {
+			t.Errorf("%s: expected message kept to be %t, got %d messages", policy, kept, len(page.Messages))
+		}
+
+		stored, err := g.Database.UserManager.Get(id)
+		if kept && stored.Name != models.TombstoneUser(id).Name {
+			t.Errorf("%s: expected name %q, got %q", policy, models.TombstoneUser(id).Name, stored.Name)
+		}
+
+		if !kept && err != models.ErrNoRecords {
+			t.Errorf("%s: expected %v, got %v", policy, models.ErrNoRecords, err)
+		}
+	}
+}
+
+func TestUserSessions(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := map[string]string{}
+	other := map[string]string{}
+
+	for _, cookies := range []map[string]string{current, other} {
+		res := doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("login: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+		}
+	}
+
+	listSessions := func() []SessionInfo {
+		res := doRequest(t, app, current, fiber.MethodGet, "/api/user/sessions", nil)
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("list: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+		}
+
+		return decodeData[[]SessionInfo](t, res)
+	}
+
+	sessions := listSessions()
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	var otherId string
+
+	for _, session := range sessions {
+		if session.IP == "" || session.CreatedAt.IsZero() || session.LastSeenAt.IsZero() {
+			t.Errorf("expected the session information, got %+v", session)
+		}
+
+		if !session.Current {
+			otherId = session.Id
+		}
+	}
+
+	res := doRequest(t, app, current, fiber.MethodDelete, "/api/user/sessions/"+otherId, nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("revoke: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, current, fiber.MethodDelete, "/api/user/sessions/"+otherId, nil)
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("revoke again: expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	res = doRequest(t, app, other, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("revoked session: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	if sessions := listSessions(); len(sessions) != 1 || !sessions[0].Current {
+		t.Errorf("expected only the current session, got %+v", sessions)
+	}
+
+	// Log out everywhere.
+	res = doRequest(t, app, current, fiber.MethodDelete, "/api/user/sessions", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("revoke all: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, current, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("current session: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+// The sessions revoked while one of their requests runs stay revoked.
+func TestUserSessionRevokedDuringRequest(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	userId, cookies := newLoggedInUser(t, app, g, "edwin")
+
+	// The session is revoked by another request while this one runs.
+	slow := fiber.New()
+	slow.Use(g.ManageSession)
+	slow.Get("/slow", g.RequireAuth, func(c *fiber.Ctx) error {
+		err := g.Sessions.RevokeAll(userId, "")
+		if err != nil {
+			return err
+		}
+
+		return c.SendString("done")
+	})
+
+	res := doRequest(t, slow, cookies, fiber.MethodGet, "/slow", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("slow: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, cookies, fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("revoked session: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+// Decodes the data of a success response.
+func decodeData[T any](t *testing.T, res *http.Response) T {
+	t.Helper()
+
+	var body SuccessMessage[T]
+
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Data
+}
+
+func TestAuthTokens(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/token", user)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("token: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	pair := decodeData[tokens.Pair](t, res)
+
+	bearer := func(accessToken string) map[string]string {
+		return map[string]string{fiber.HeaderAuthorization: "Bearer " + accessToken}
+	}
+
+	// The token and the session cookie resolve to the same user.
+	res = doRequestWithHeaders(t, app, map[string]string{}, bearer(pair.AccessToken), fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("data: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	fromToken := decodeData[models.User](t, res)
+
+	cookies := map[string]string{}
+	doRequest(t, app, cookies, fiber.MethodPost, "/api/user/login", user)
+
+	res = doRequest(t, app, cookies, fiber.MethodGet, "/api/user/data", nil)
+	if fromSession := decodeData[models.User](t, res); fromSession.Id != fromToken.Id {
+		t.Errorf("expected user %d, got %d", fromSession.Id, fromToken.Id)
+	}
+
+	res = doRequestWithHeaders(t, app, map[string]string{}, bearer("foo"), fiber.MethodGet, "/api/user/data", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("bad token: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	// The refresh tokens can be used only once.
+	request := RefreshTokenRequest{RefreshToken: pair.RefreshToken}
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/refresh", request)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("refresh: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	refreshed := decodeData[tokens.Pair](t, res)
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/refresh", request)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("refresh again: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	request = RefreshTokenRequest{RefreshToken: refreshed.RefreshToken}
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/revoke", request)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("revoke: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/refresh", request)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("refresh revoked: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	// The access tokens are rejected along with their refresh tokens.
+	for _, accessToken := range []string{pair.AccessToken, refreshed.AccessToken} {
+		res = doRequestWithHeaders(t, app, map[string]string{}, bearer(accessToken), fiber.MethodGet, "/api/user/data", nil)
+		if res.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("revoked access token: expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+		}
+	}
+}
+
+func TestUserLoginLockout(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := g.Database.UserManager.New(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := models.User{Name: user.Name, Password: "wrong"}
+	config := DefaultConfig()
+
+	for i := 0; i <= config.Login.FreeAttempts; i++ {
+		res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", wrong)
+		if res.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i, fiber.StatusUnauthorized, res.StatusCode)
+		}
+	}
+
+	// Even the right password is rejected while locked.
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", user)
+	if res.StatusCode != fiber.StatusTooManyRequests || res.Header.Get(fiber.HeaderRetryAfter) == "" {
+		t.Fatalf("expected status %d with a Retry-After header, got %d", fiber.StatusTooManyRequests, res.StatusCode)
+	}
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/token", user)
+	if res.StatusCode != fiber.StatusTooManyRequests {
+		t.Errorf("token: expected status %d, got %d", fiber.StatusTooManyRequests, res.StatusCode)
+	}
+
+	err = g.Lockout.Reset(lockout.UserKey(user.Name), lockout.IPKey("0.0.0.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/login", user)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("unlocked: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+}
+
+// The failures are counted by the address set by the trusted proxies.
+func TestUserLoginLockoutBehindProxy(t *testing.T) {
+	_, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	config := DefaultConfig()
+	config.Server.ProxyHeader = "X-Real-IP"
+
+	login := func(app *fiber.App, ip string, attempt int) int {
+		headers := map[string]string{"X-Real-IP": ip}
+		wrong := models.User{Name: fmt.Sprintf("user%d", attempt), Password: "wrong"}
+
+		res := doRequestWithHeaders(t, app, map[string]string{}, headers, fiber.MethodPost, "/api/user/login", wrong)
+		return res.StatusCode
+	}
+
+	// The requests of the tests come from 0.0.0.0.
+	config.Server.TrustedProxies = []string{"0.0.0.0"}
+	app := g.Setup(config)
+
+	for i := 0; i <= config.Login.FreeAttempts; i++ {
+		login(app, "10.0.0.1", i)
+	}
+
+	if status := login(app, "10.0.0.1", 100); status != fiber.StatusTooManyRequests {
+		t.Errorf("locked client: expected status %d, got %d", fiber.StatusTooManyRequests, status)
+	}
+
+	if status := login(app, "10.0.0.2", 101); status != fiber.StatusUnauthorized {
+		t.Errorf("other client: expected status %d, got %d", fiber.StatusUnauthorized, status)
+	}
+
+	// The header of the proxies not trusted is ignored.
+	config.Server.TrustedProxies = []string{"10.9.9.9"}
+	app = g.Setup(config)
+
+	for i := 0; i <= config.Login.FreeAttempts; i++ {
+		login(app, "10.0.0.3", 200+i)
+	}
+
+	if status := login(app, "10.0.0.4", 300); status != fiber.StatusTooManyRequests {
+		t.Errorf("untrusted proxy: expected status %d, got %d", fiber.StatusTooManyRequests, status)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	config := DefaultConfig()
+	config.RateLimit[RateLimitGroupUser] = RateLimitRule{Limit: 2, Window: 3600}
+	g.ApplySettings(config)
+
+	for i := 1; i <= 3; i++ {
+		res := doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/user/status", nil)
+
+		status := fiber.StatusOK
+		if i > 2 {
+			status = fiber.StatusTooManyRequests
+		}
+
+		if res.StatusCode != status {
+			t.Errorf("request %d: expected status %d, got %d", i, status, res.StatusCode)
+		}
+
+		if res.Header.Get("RateLimit-Limit") != "2" || res.Header.Get("RateLimit-Reset") == "" {
+			t.Errorf("request %d: expected the RateLimit headers, got %v", i, res.Header)
+		}
+	}
+
+	// The other groups have their own limits.
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/auth/revoke", RefreshTokenRequest{})
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("auth: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+}
+
+func TestUserSignUpPasswordPolicy(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "edwin",
+		Birthdate: "2000-01-01",
+	}
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", fiber.StatusBadRequest, res.StatusCode)
+	}
+
+	var body struct {
+		Err     string               `json:"err"`
+		Details []password.Violation `json:"details"`
+	}
+
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every broken rule is reported.
+	var rules []string
+	for _, violation := range body.Details {
+		rules = append(rules, violation.Rule)
+	}
+
+	expected := []string{password.RuleMinLength, password.RuleDigit, password.RuleSymbol, password.RuleUserName}
+
+	if body.Err != ErrPasswordNotValid.Error() || !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected err=%q rules=%v, got err=%q rules=%v", ErrPasswordNotValid, expected, body.Err, rules)
+	}
+
+	user.Password = "secreto#123"
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusCreated {
+		t.Errorf("expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+}
+
+func TestUserSignUpValidation(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      " ",
+		Password:  "secreto#123",
+		Birthdate: "01/01/2000",
+	}
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", fiber.StatusBadRequest, res.StatusCode)
+	}
+
+	var body struct {
+		Err     string                 `json:"err"`
+		Details []validator.FieldError `json:"details"`
+	}
+
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every invalid field is reported.
+	expected := []validator.FieldError{
+		{Field: "name", Code: models.ErrUserNameEmpty},
+		{Field: "birthdate", Code: models.ErrUserBirthdateBadFormat},
+	}
+
+	if body.Err != ErrValidationFailed.Error() || !reflect.DeepEqual(body.Details, expected) {
+		t.Errorf("expected err=%q details=%v, got err=%q details=%v", ErrValidationFailed, expected, body.Err, body.Details)
+	}
+
+	// The length of the name is counted in characters, not bytes.
+	user.Name = strings.Repeat("ñ", models.UserNameMaxLength)
+	user.Birthdate = "2000-01-01"
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusCreated {
+		t.Errorf("expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	app.Get("/test/fail", func(c *fiber.Ctx) error {
+		return errors.New("secret connection string")
+	})
+
+	var body struct {
+		Err     string `json:"err"`
+		Details string `json:"details"`
+	}
+
+	// The status comes from the code of the error.
+	res := doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/user/sessions", nil)
+	if res.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", fiber.StatusUnauthorized, res.StatusCode)
+	}
+
+	// The errors without a code are never sent to the client.
+	res = doRequest(t, app, map[string]string{}, fiber.MethodGet, "/test/fail", nil)
+	if res.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", fiber.StatusInternalServerError, res.StatusCode)
+	}
+
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Err != ErrServerInternal.Error() || strings.Contains(body.Details, "secret") {
+		t.Errorf("expected err=%q without the original error, got err=%q details=%q", ErrServerInternal, body.Err, body.Details)
+	}
+
+	// The errors of Fiber get a code as well.
+	res = doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/unknown", nil)
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Err != ErrResourceNotFound.Error() {
+		t.Errorf("expected err=%q, got err=%q", ErrResourceNotFound, body.Err)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "lucia",
+		Password:  "secreto#123",
+		Birthdate: "2000-01-01",
+	}
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+
+	var body struct {
+		Err     string `json:"err"`
+		Details string `json:"details"`
+	}
+
+	// The language is taken from the header `Accept-Language`.
+	headers := map[string]string{fiber.HeaderAcceptLanguage: "en-US,en;q=0.9,es;q=0.8"}
+
+	res = doRequestWithHeaders(t, app, map[string]string{}, headers, fiber.MethodPost, "/api/user/signup", user)
+	if res.StatusCode != fiber.StatusConflict {
+		t.Fatalf("expected status %d, got %d", fiber.StatusConflict, res.StatusCode)
+	}
+
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "The user name lucia already exists"
+	if body.Err != models.ErrUserNameExists.Error() || body.Details != expected {
+		t.Errorf("expected err=%q details=%q, got err=%q details=%q", models.ErrUserNameExists, expected, body.Err, body.Details)
+	}
+
+	// The language chosen by the user takes precedence over the header.
+	cookies := map[string]string{}
+
+	res = doRequest(t, app, cookies, fiber.MethodPut, "/api/user/language", LanguageRequest{Language: "fr"})
+	if res.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", fiber.StatusBadRequest, res.StatusCode)
+	}
+
+	res = doRequest(t, app, cookies, fiber.MethodPut, "/api/user/language", LanguageRequest{Language: "es"})
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	res = doRequestWithHeaders(t, app, cookies, headers, fiber.MethodPost, "/api/user/signup", user)
+
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "El nombre de usuario lucia ya existe"
+	if body.Details != expected {
+		t.Errorf("expected details=%q, got details=%q", expected, body.Details)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodGet, "/healthz", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Errorf("healthz: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	// The probes don't create sessions.
+	cookies := map[string]string{}
+
+	res = doRequest(t, app, cookies, fiber.MethodGet, "/readyz", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("readyz: expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	if len(cookies) > 0 {
+		t.Errorf("readyz: expected no cookies, got %v", cookies)
+	}
+
+	report := decodeData[health.Report](t, res)
+
+	for _, name := range []string{"redis", "images", "disk"} {
+		if report.Checks[name].Status != health.StatusOk {
+			t.Errorf("readyz: expected the check %s passed, got %+v", name, report.Checks[name])
+		}
+	}
+
+	// A failed check makes the server not ready.
+	g.Health.Add("broken", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	})
+
+	res = doRequest(t, app, map[string]string{}, fiber.MethodGet, "/readyz", nil)
+	if res.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("readyz: expected status %d, got %d", fiber.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	var body struct {
+		Err     string        `json:"err"`
+		Details health.Report `json:"details"`
+	}
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = json.Unmarshal(raw, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The error of the check is not exposed.
+	if bytes.Contains(raw, []byte("unreachable")) {
+		t.Errorf("readyz: expected the error of the check to be hidden, got %s", raw)
+	}
+
+	if body.Err != ErrServiceUnavailable.Error() || body.Details.Checks["broken"].Status != health.StatusFail {
+		t.Errorf("readyz: expected err=%q and the failed check, got %+v", ErrServiceUnavailable, body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	user := models.User{
+		Name:      "marta",
+		Password:  "secreto#123",
+		Birthdate: "2000-01-01",
+	}
+
+	doRequest(t, app, map[string]string{}, fiber.MethodPost, "/api/user/signup", user)
+	doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/user/status", nil)
+	doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/conversations/1", nil)
+	doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/unknown", nil)
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodGet, "/metrics", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The routes are labeled with their pattern, the unknown paths and
+	// the requests rejected by the middlewares share the same label.
+	expected := []string{
+		`nameless_http_requests_total{method="POST",route="/api/user/signup",status="201"} 1`,
+		`nameless_http_requests_total{method="GET",route="/api/user/status",status="200"} 1`,
+		`nameless_http_requests_total{method="GET",route="unmatched",status="401"} 1`,
+		`nameless_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`nameless_http_request_duration_seconds_count{method="POST",route="/api/user/signup"} 1`,
+		`nameless_password_hash_duration_seconds_count{algorithm="bcrypt",operation="hash"} 1`,
+		`nameless_session_storage_duration_seconds_count{operation="set"}`,
+		`go_goroutines`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected the metrics to contain %q", line)
+		}
+	}
+}
+
+// Fails when the routes and the checked-in OpenAPI document drift
+// apart, run `go test ./cmd/api -run TestOpenAPI -update` to
+// regenerate the document after changing the routes.
+func TestOpenAPI(t *testing.T) {
+	app, _ := newTestApp(t, models.DeletionPolicyTombstone)
+
+	_, err := NewOpenAPI(app.GetRoutes(true))
+	if err != nil {
+		t.Fatalf("%s, document them in routeDocs", err)
+	}
+
+	// Every documented route must be registered.
+	registered := map[string]bool{}
+	for _, route := range app.GetRoutes(true) {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for key := range routeDocs {
+		if !registered[key] {
+			t.Errorf("documented route %q is not registered", key)
+		}
+	}
+
+	res := doRequest(t, app, map[string]string{}, fiber.MethodGet, "/api/openapi.json", nil)
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status %d, got %d", fiber.StatusOK, res.StatusCode)
+	}
+
+	generated, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *updateOpenAPI {
+		err := os.WriteFile(openAPIPath, generated, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkedIn, err := os.ReadFile(openAPIPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(generated, checkedIn) {
+		t.Errorf("%s is outdated, run `go test ./cmd/api -run TestOpenAPI -update`", openAPIPath)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	app, g := newTestApp(t, models.DeletionPolicyTombstone)
+
+	g.MatchQueue = matchmaking.New(g.Database.ConversationManager, matchmaking.Options{
+		Duration: 300,
+		Timeout:  100 * time.Millisecond,
+	}, g.Logger)
+
+	_, edwinCookies := newLoggedInUser(t, app, g, "edwin")
+	_, carlosCookies := newLoggedInUser(t, app, g, "carlos")
+
+	// Nobody else is waiting, the client must poll again.
+	res := doRequest(t, app, edwinCookies, fiber.MethodPost, "/api/match", nil)
+	if res.StatusCode != fiber.StatusNotFound {
+		t.Errorf("alone: expected status %d, got %d", fiber.StatusNotFound, res.StatusCode)
+	}
+
+	statuses := make(chan int, 1)
+
+	go func() {
+		res := doRequest(t, app, edwinCookies, fiber.MethodPost, "/api/match", nil)
+		statuses <- res.StatusCode
+	}()
+
+	// Carlos joins while Edwin waits.
+	time.Sleep(20 * time.Millisecond)
+
+	res = doRequest(t, app, carlosCookies, fiber.MethodPost, "/api/match", nil)
+	if res.StatusCode != fiber.StatusCreated {
+		t.Errorf("matched: expected status %d, got %d", fiber.StatusCreated, res.StatusCode)
+	}
+
+	if conversation := decodeData[models.Conversation](t, res); !conversation.Matched {
+		t.Error("expected the conversation to be marked as matched")
+	}
+
+	if status := <-statuses; status != fiber.StatusCreated {
+		t.Errorf("partner: expected status %d, got %d", fiber.StatusCreated, status)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+	"github.com/Edwing123/udem-chat-app/pkg/health"
+	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+)
+
+// Creates the checker of the dependencies of the server:
+//   - redis: the sessions storage answers.
+//   - database: the SQL database answers, sqldb is nil for `DriverMemory`.
+//   - images: a file can be created in the directory of the served images.
+//   - disk: the disk of the appdata directory has enough free space.
+func NewHealthChecker(config Config, storage fiber.Storage, sqldb *sql.DB, profileManager *profile.Manager) *health.Checker {
+	checker := health.New(health.Options{
+		Timeout:  time.Duration(config.Health.Timeout) * time.Second,
+		CacheTTL: time.Duration(config.Health.CacheTTL) * time.Second,
+	})
+
+	checker.Add("redis", storageCheck(storage))
+
+	if sqldb != nil {
+		checker.Add("database", sqldb.PingContext)
+	}
+
+	checker.Add("images", health.Writable(profileManager.ActiveDir()))
+	checker.Add("disk", health.FreeDisk(config.AppData, config.Health.MinFreeDisk))
+
+	return checker
+}
+
+// Pings Redis, the other storages (the
+// memory one of the tests) are read instead.
+func storageCheck(storage fiber.Storage) health.Check {
+	if redisStorage, ok := storage.(*redis.Storage); ok {
+		return func(ctx context.Context) error {
+			return redisStorage.Conn().Ping(ctx).Err()
+		}
+	}
+
+	return func(ctx context.Context) error {
+		_, err := storage.Get("health_check")
+		return err
+	}
+}
+
+// Handler for the liveness probe, it only
+// reports that the server is answering.
+func (g *Global) Healthz(c *fiber.Ctx) error {
+	return SendSucessMessage(c, fiber.StatusOK, health.StatusOk)
+}
+
+// Handler for the readiness probe, it fails with `ErrServiceUnavailable`
+// if any dependency is not usable, the details have the result of
+// every check either way. The errors of the checks are only logged.
+func (g *Global) Readyz(c *fiber.Ctx) error {
+	report := g.Health.Run()
+
+	if report.Status != health.StatusOk {
+		for name, result := range report.Checks {
+			if result.Err != nil {
+				g.Logger.Error("readiness check fail", result.Err, "check", name)
+			}
+		}
+
+		return codes.WithDetails(ErrServiceUnavailable, report)
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, report)
+}
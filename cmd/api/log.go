@@ -6,7 +6,17 @@ import (
 	"golang.org/x/exp/slog"
 )
 
-func NewLogger(output io.Writer) *slog.Logger {
-	logger := slog.New(slog.NewTextHandler(output))
+// Levels of the logger by name, see `Config.Log`.
+var LogLevels = map[string]slog.Level{
+	"debug": slog.DebugLevel,
+	"info":  slog.InfoLevel,
+	"warn":  slog.WarnLevel,
+	"error": slog.ErrorLevel,
+}
+
+// Creates a logger of the records with the level or above,
+// pass a `slog.LevelVar` to change the level later.
+func NewLogger(output io.Writer, level slog.Leveler) *slog.Logger {
+	logger := slog.New(slog.HandlerOptions{Level: level}.NewTextHandler(output))
 	return logger
 }
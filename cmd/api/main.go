@@ -4,40 +4,41 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path"
+	"syscall"
 	"time"
 
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
 	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
-	sqlserver "github.com/Edwing123/udem-chat-app/pkg/models/sql-server"
+	"github.com/Edwing123/udem-chat-app/pkg/matchmaking"
+	"github.com/Edwing123/udem-chat-app/pkg/ratelimit"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
 	_ "github.com/microsoft/go-mssqldb"
+	"golang.org/x/exp/slog"
 )
 
 func main() {
-	// Get command line flags.
-	flags := GetFlags()
-
-	// Load configuration.
-	config, err := LoadConfig(flags.ConfigPath)
-	if err != nil {
-		log.Fatalln("failed loading config: ", err)
+	// Run the subcommand instead of the server if one was passed.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(RunMigrate(os.Args[2:]))
+		case "unlock":
+			os.Exit(RunUnlock(os.Args[2:]))
+		case "config":
+			os.Exit(RunConfig(os.Args[2:]))
+		}
 	}
 
-	// Validate the configuration.
-	configValidationErrors := ValidateConfig(config)
-	if configValidationErrors != nil {
-		fmt.Println("Configuration validation failed with the following errors:")
-		fmt.Println()
-
-		for _, err := range configValidationErrors {
-			fmt.Printf("\t- %s\n", err)
-		}
+	// Get command line flags.
+	flags := GetFlags()
 
-		fmt.Println()
-		os.Exit(1)
-	}
+	// Load and validate the configuration.
+	config := MustLoadConfig(flags)
 
 	// Create appdata directories.
-	err = CreateAppDataDirs(config.AppData)
+	err := CreateAppDataDirs(config.AppData)
 	if err != nil {
 		fmt.Println("An error occured while creating appdata dirs:")
 		fmt.Println()
@@ -59,25 +60,34 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	defer logsFile.Close()
 
 	// Create logger, the output generated by it will be
-	// stored to the logs file.
-	logger := NewLogger(logsFile)
+	// stored to the logs file. Its level can be reloaded.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(LogLevels[config.Log.Level])
 
-	// Create sessions store.
-	store := NewSessionStore(
-		NewRedisStorage(config.Redis),
-	)
+	logger := NewLogger(logsFile, logLevel)
 
-	// Remember to close the store's underlying storage.
-	defer func() {
-		store.Storage.Close()
-		logger.Info("storage closed")
-	}()
+	// Create the metrics exposed at /metrics.
+	metrics := NewMetrics()
+
+	// Create sessions store, its underlying storage
+	// is closed once the server shuts down.
+	redisStorage := NewRedisStorage(config.Redis)
+	store := NewSessionStore(metrics.InstrumentStorage(redisStorage))
+
+	// Create the manager of the bearer tokens, the refresh tokens
+	// are kept along with the sessions. It's given the Redis storage
+	// itself, so it can use each refresh token once atomically.
+	tokenManager, err := NewTokenManager(config, redisStorage, logger)
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	// Setup profile manager.
 	profileManager := profile.New(path.Join(config.AppData, "images"), logger)
+	profileManager.Observe(metrics.ObserveImageStage)
+
 	err = profileManager.InitDirs()
 	if err != nil {
 		fmt.Println("An error occured while creating profile manager dirs:")
@@ -89,10 +99,14 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create connection to SQL Server database.
-	sqldb, err := NewSQLServerDatabase(config.Database)
+	// Create the implementation of `models.Database`
+	// selected by the database driver.
+	hasher := NewHasher(config)
+	hasher.Observe(metrics.ObservePasswordHash)
+
+	databaseImpl, sqldb, err := NewDatabase(config.Database, hasher, logger)
 	if err != nil {
-		fmt.Println("An error occured while connecting to sql server database:")
+		fmt.Println("An error occured while connecting to the database:")
 		fmt.Println()
 
 		fmt.Println(err)
@@ -101,18 +115,81 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create an implementation of `models.Database` using
-	// SQL Server as the database.
-	databaseImpl := sqlserver.New(sqldb, logger)
+	if sqldb != nil {
+		metrics.RegisterDatabase(sqldb)
+	}
+
+	// Create the hub for pushing events
+	// to the WebSocket connections.
+	hub := realtime.New(logger)
+
+	// Create the queue for matching users with strangers.
+	matchQueue := matchmaking.New(
+		databaseImpl.ConversationManager,
+		matchmaking.Options{
+			Duration: config.Match.Duration,
+			Timeout:  time.Duration(config.Match.Timeout) * time.Second,
+		},
+		logger,
+	)
+
+	// Schedule the end of the conversations that have a time
+	// limit, including the ones created before the server started.
+	expiry := NewExpiryScheduler(databaseImpl.ConversationManager, hub, logger)
+
+	err = expiry.Reschedule()
+	if err != nil {
+		fmt.Println("An error occured while scheduling conversations expiry:")
+		fmt.Println()
+
+		fmt.Println(err)
 
+		fmt.Println()
+		os.Exit(1)
+	}
+
+	// Load the messages of every language.
+	messages, err := i18n.New(i18n.DefaultLanguage)
+	if err != nil {
+		fmt.Println("An error occured while loading the messages:")
+		fmt.Println()
+
+		fmt.Println(err)
+
+		fmt.Println()
+		os.Exit(1)
+	}
+
+	// The sessions index, the login limiter, the rate limiter and the
+	// health checker use the Redis storage itself, the indexes and the
+	// counters are updated with the Redis client.
 	global := Global{
 		Logger:         logger,
 		Store:          store,
+		Sessions:       NewSessionIndex(redisStorage),
+		Tokens:         tokenManager,
+		Lockout:        NewLoginLimiter(config, redisStorage),
+		RateLimiter:    ratelimit.New(redisStorage, logger),
 		ProfileManager: &profileManager,
 		Database:       &databaseImpl,
+		Hub:            hub,
+		MatchQueue:     matchQueue,
+		Expiry:         expiry,
+		Messages:       messages,
+		Health:         NewHealthChecker(config, redisStorage, sqldb, &profileManager),
+		Metrics:        metrics,
+		DeletionPolicy: config.User.DeletionPolicy,
+		PasswordPolicy: NewPasswordPolicy(config),
+		LogLevel:       logLevel,
 	}
 
-	app := global.Setup()
+	global.ApplySettings(config)
+
+	// Reload the configuration on SIGHUP.
+	reloader := NewReloader(&global, flags, config)
+	stopReloads := reloader.Watch()
+
+	app := global.Setup(config)
 	addr := config.Server.Addr
 
 	app.Hooks().OnListen(func() error {
@@ -120,7 +197,51 @@ func main() {
 		return nil
 	})
 
-	err = app.Listen(addr)
+	// Shut down on SIGINT and SIGTERM.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	listenErr := make(chan error, 1)
+
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	select {
+	case err = <-listenErr:
+		logger.Error("server listen fail", err)
+		expiry.Stop()
+
+	case sig := <-signals:
+		logger.Info("server shutdown start", "signal", sig.String())
+
+		err = global.Shutdown(app, time.Duration(config.Server.ShutdownTimeout)*time.Second)
+		if err != nil {
+			logger.Error("server shutdown incomplete", err)
+		}
+	}
+
+	signal.Stop(signals)
+	stopReloads()
+
+	// Close the connections, the logs file goes
+	// last so the errors can still be logged.
+	err = store.Storage.Close()
+	if err != nil {
+		logger.Error("storage close", err)
+	} else {
+		logger.Info("storage closed")
+	}
+
+	if sqldb != nil {
+		err = sqldb.Close()
+		if err != nil {
+			logger.Error("database close", err)
+		} else {
+			logger.Info("database closed")
+		}
+	}
 
-	logger.Error("server listen fail", err)
+	logger.Info("server shutdown complete")
+	logsFile.Close()
 }
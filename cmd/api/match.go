@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler for matching the logged-in user with a stranger, the
+// response is sent once a partner is found, the wait times out
+// or the user cancels it. The wait is short, the clients poll
+// until matched, so the users whose client went away leave the
+// queue soon.
+func (g *Global) MatchJoin(c *fiber.Ctx) error {
+	userId := g.GetUserId(c)
+
+	result, err := g.MatchQueue.Join(c.UserContext(), userId)
+	if err != nil {
+		return err
+	}
+
+	match := <-result
+	if match.Err != nil {
+		return match.Err
+	}
+
+	g.Expiry.Schedule(match.Conversation)
+
+	// The response could be lost if the client went
+	// away while waiting, so push the match as well.
+	g.Hub.Publish([]int{userId}, realtime.Event{
+		Type: realtime.EventMatchFound,
+		Data: match.Conversation,
+	})
+
+	return SendSucessMessage(c, fiber.StatusCreated, match.Conversation)
+}
+
+// Handler for canceling the wait of the logged-in user.
+func (g *Global) MatchCancel(c *fiber.Ctx) error {
+	err := g.MatchQueue.Cancel(g.GetUserId(c))
+	if err != nil {
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, g.Message(c, "match.canceled", nil))
+}
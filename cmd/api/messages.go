@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler for sending a message to a conversation.
+func (g *Global) MessageNew(c *fiber.Ctx) error {
+	request, err := ReadBodyFromRequest[NewMessageRequest](c)
+	if err != nil {
+		return err
+	}
+
+	v := models.CheckMessageContent(request.Content)
+	if !v.Valid() {
+		return ValidationError(v)
+	}
+
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	// The conversation could have expired before being ended by the scheduler.
+	if conversation.IsExpired(time.Now()) {
+		return models.ErrConversationExpired
+	}
+
+	if conversation.EndedAt != nil {
+		return models.ErrConversationEnded
+	}
+
+	message, err := g.Database.MessageManager.New(models.Message{
+		Content:        request.Content,
+		UserId:         g.GetUserId(c),
+		ConversationId: conversation.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	g.Hub.Publish(conversation.Participants, realtime.Event{
+		Type: realtime.EventMessageNew,
+		Data: message,
+	})
+
+	return SendSucessMessage(c, fiber.StatusCreated, message)
+}
+
+// Handler for getting a page of the messages of a conversation,
+// the page is selected with the query parameters `before`,
+// `after` and `limit`.
+func (g *Global) MessageHistory(c *fiber.Ctx) error {
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	// A bad limit falls back to the default one.
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	page, err := g.Database.MessageManager.History(conversation.Id, models.HistoryQuery{
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+		Limit:  limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, page)
+}
+
+// Handler for getting a message of a conversation.
+func (g *Global) MessageGet(c *fiber.Ctx) error {
+	conversation, err := g.getJoinedConversation(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.ParamsInt("messageId")
+	if err != nil {
+		return models.ErrNoRecords
+	}
+
+	message, err := g.Database.MessageManager.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// Don't leak messages of other conversations.
+	if message.ConversationId != conversation.Id {
+		return models.ErrNoRecords
+	}
+
+	return SendSucessMessage(c, fiber.StatusOK, message)
+}
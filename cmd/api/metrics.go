@@ -0,0 +1,258 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// Prefix of the names of the metrics.
+const metricsNamespace = "nameless"
+
+// Label of the requests that didn't reach the handler of a route,
+// either because their path is unknown (so the unknown paths don't
+// create a series each) or because a middleware rejected them.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the Prometheus metrics of the server, they're
+// kept in their own registry and exposed at `/metrics`.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	// The first handler of every route, see `Metrics.SetRoutes`.
+	routes map[*fiber.Handler]struct{}
+
+	// Requests by method, route and status.
+	requests *prometheus.CounterVec
+
+	// Duration of the requests by method and route.
+	requestDuration *prometheus.HistogramVec
+
+	// Duration and errors of the operations of the
+	// sessions storage (Redis in production).
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+
+	// Duration and failures of the stages of the
+	// processing of the profile pictures.
+	imageStageDuration *prometheus.HistogramVec
+	imageStageFailures *prometheus.CounterVec
+
+	// Duration of the hashes and verifications of the passwords.
+	passwordHashDuration *prometheus.HistogramVec
+}
+
+// Creates the metrics of the server, along with the
+// metrics of the Go runtime and of the process.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "session_storage_duration_seconds",
+			Help:      "Duration of the operations of the sessions storage.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "session_storage_errors_total",
+			Help:      "Number of failed operations of the sessions storage.",
+		}, []string{"operation"}),
+
+		imageStageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "image_stage_duration_seconds",
+			Help:      "Duration of the stages of the processing of the profile pictures.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"stage"}),
+
+		imageStageFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "image_stage_failures_total",
+			Help:      "Number of failed stages of the processing of the profile pictures.",
+		}, []string{"stage"}),
+
+		passwordHashDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "password_hash_duration_seconds",
+			Help:      "Duration of the hashes and verifications of the passwords by algorithm.",
+			Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"algorithm", "operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storageDuration,
+		m.storageErrors,
+		m.imageStageDuration,
+		m.imageStageFailures,
+		m.passwordHashDuration,
+	)
+
+	return m
+}
+
+// Sets the routes of the app, the requests are labeled with the route
+// that handled them. It must be called once every route is registered.
+//
+// Fiber reports the middleware (for example `RequireAuth`) as the route of
+// the requests rejected by it, and the middlewares aren't told apart from
+// the routes. The routes are copies sharing their handlers with the routes
+// of the app, so their handlers are recognized by address.
+func (m *Metrics) SetRoutes(routes []fiber.Route) {
+	m.routes = map[*fiber.Handler]struct{}{}
+
+	for _, route := range routes {
+		if len(route.Handlers) > 0 {
+			m.routes[&route.Handlers[0]] = struct{}{}
+		}
+	}
+}
+
+// Returns the pattern of the route that handled the request, or
+// `unmatchedRoute` if the request didn't reach a route handler.
+func (m *Metrics) routeLabel(c *fiber.Ctx) string {
+	route := c.Route()
+
+	if len(route.Handlers) == 0 {
+		return unmatchedRoute
+	}
+
+	if _, ok := m.routes[&route.Handlers[0]]; !ok {
+		return unmatchedRoute
+	}
+
+	return route.Path
+}
+
+// Adds the statistics of the connection pool of the SQL database.
+func (m *Metrics) RegisterDatabase(sqldb *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(sqldb, metricsNamespace))
+}
+
+// Handler for exposing the metrics in the Prometheus text format.
+func (m *Metrics) Handler() fiber.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(
+		promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}),
+	)
+
+	return func(c *fiber.Ctx) error {
+		handler(c.Context())
+		return nil
+	}
+}
+
+// This middleware counts the requests and measures their duration,
+// the routes are labeled with their pattern (`/api/conversations/:id<int>`)
+// rather than the path of the request.
+func (m *Metrics) Middleware(c *fiber.Ctx) error {
+	start := time.Now()
+
+	// The errors are turned into responses
+	// here, so their status can be counted.
+	err := c.Next()
+	if err != nil {
+		err = c.App().ErrorHandler(c, err)
+		if err != nil {
+			c.Status(fiber.StatusInternalServerError)
+		}
+	}
+
+	// The method is copied, the strings of the request are reused by Fiber.
+	method := utils.CopyString(c.Method())
+	route := m.routeLabel(c)
+	status := strconv.Itoa(c.Response().StatusCode())
+
+	m.requests.WithLabelValues(method, route, status).Inc()
+	m.requestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+// Observes a stage of the processing of a profile picture,
+// see `profile.Manager.Observe`.
+func (m *Metrics) ObserveImageStage(stage string, duration time.Duration, err error) {
+	m.imageStageDuration.WithLabelValues(stage).Observe(duration.Seconds())
+
+	if err != nil {
+		m.imageStageFailures.WithLabelValues(stage).Inc()
+	}
+}
+
+// Observes a hash or a verification of a password,
+// see `hashing.Manager.Observe`.
+func (m *Metrics) ObservePasswordHash(algorithm, operation string, duration time.Duration) {
+	m.passwordHashDuration.WithLabelValues(algorithm, operation).Observe(duration.Seconds())
+}
+
+// Returns the storage measuring the duration and
+// counting the errors of the operations of storage.
+func (m *Metrics) InstrumentStorage(storage fiber.Storage) fiber.Storage {
+	return &instrumentedStorage{
+		Storage: storage,
+		metrics: m,
+	}
+}
+
+type instrumentedStorage struct {
+	fiber.Storage
+
+	metrics *Metrics
+}
+
+func (s *instrumentedStorage) observe(operation string, start time.Time, err error) {
+	s.metrics.storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		s.metrics.storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (s *instrumentedStorage) Get(key string) ([]byte, error) {
+	start := time.Now()
+	value, err := s.Storage.Get(key)
+	s.observe("get", start, err)
+
+	return value, err
+}
+
+func (s *instrumentedStorage) Set(key string, value []byte, exp time.Duration) error {
+	start := time.Now()
+	err := s.Storage.Set(key, value, exp)
+	s.observe("set", start, err)
+
+	return err
+}
+
+func (s *instrumentedStorage) Delete(key string) error {
+	start := time.Now()
+	err := s.Storage.Delete(key)
+	s.observe("delete", start, err)
+
+	return err
+}
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"strings"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/tokens"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -8,22 +12,71 @@ const (
 	SessionKey    string = "session_key"
 	UserIdKey     string = "user_id_key"
 	IsLoggedInKey string = "is_logged_in"
+
+	// Language chosen by the user for the messages.
+	LanguageKey string = "language"
+
+	// Information of the session shown in the sessions list,
+	// the times are stored as Unix timestamps.
+	SessionDeviceKey     string = "session_device"
+	SessionIPKey         string = "session_ip"
+	SessionCreatedAtKey  string = "session_created_at"
+	SessionLastSeenAtKey string = "session_last_seen_at"
+
+	// Context locals of the requests authenticated with a bearer token.
+	TokenUserIdKey string = "token_user_id"
+	TokenIdKey     string = "token_id"
 )
 
-// Calls the next handler only if the user is logged in.
+// Calls the next handler only if the user is logged in, either with
+// a session cookie or with an access token (`Authorization: Bearer`).
 func (g *Global) RequireAuth(c *fiber.Ctx) error {
+	authorization := c.Get(fiber.HeaderAuthorization)
+	if authorization != "" {
+		return g.requireToken(c, authorization)
+	}
+
 	sess := g.GetSession(c)
 	isLoggedIn, ok := sess.Get(IsLoggedInKey).(bool)
 
 	if !isLoggedIn || !ok {
-		return SendErrorMessage(
-			c,
-			fiber.StatusUnauthorized,
-			ErrAuthRequired,
-			"Autenticacion requerida",
-		)
+		return ErrAuthRequired
 	}
 
+	sess.Set(SessionLastSeenAtKey, time.Now().Unix())
+
+	return c.Next()
+}
+
+// Returns the token of the value of an `Authorization` header, the
+// boolean is false if the value doesn't use the "Bearer" scheme.
+func bearerToken(authorization string) (string, bool) {
+	const prefix = "Bearer "
+
+	if !strings.HasPrefix(authorization, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(authorization, prefix), true
+}
+
+// Authenticates the request with the access token of the
+// `Authorization` header, the value of the header must be
+// "Bearer <token>".
+func (g *Global) requireToken(c *fiber.Ctx, authorization string) error {
+	accessToken, ok := bearerToken(authorization)
+	if !ok {
+		return tokens.ErrTokenNotValid
+	}
+
+	userId, tokenId, err := g.Tokens.Verify(accessToken)
+	if err != nil {
+		return err
+	}
+
+	c.Locals(TokenUserIdKey, userId)
+	c.Locals(TokenIdKey, tokenId)
+
 	return c.Next()
 }
 
@@ -32,7 +85,9 @@ func (g *Global) RequireAuth(c *fiber.Ctx) error {
 // - Save the session to the context's locals.
 // - Call the next middleware.
 // - Check the returned error of the the previously called middleware.
-// - Save the session state (which could've been modified by other middlewares).
+// - Save the session state (which could've been modified by other middlewares),
+//   unless the session was revoked meanwhile.
+// - Extend the expiration of the index of the sessions of the logged-in user.
 func (g *Global) ManageSession(c *fiber.Ctx) error {
 	sess, err := g.Store.Get(c)
 	if err != nil {
@@ -42,16 +97,56 @@ func (g *Global) ManageSession(c *fiber.Ctx) error {
 
 	c.Locals(SessionKey, sess)
 
-	err = c.Next()
-	if err != nil {
-		return err
+	// The session is released once saved, so the language is kept
+	// in the locals for the responses of the failed requests.
+	if language, ok := sess.Get(LanguageKey).(string); ok {
+		c.Locals(LanguageKey, language)
+	}
+
+	// The session is released once saved, so its state is kept
+	// to tell whether it was revoked while the request ran.
+	id := sess.ID()
+	isLoggedIn, _ := sess.Get(IsLoggedInKey).(bool)
+
+	// The session is saved even if the handler failed, its
+	// error is turned into a response by `Global.ErrorHandler`.
+	handlerErr := c.Next()
+
+	// A logged-in session revoked while the request ran (for example
+	// a long-poll) is no longer stored, saving it would bring it back.
+	// The sessions regenerated by the handler have a new id.
+	if isLoggedIn && sess.ID() == id {
+		exists, err := g.Sessions.Exists(id)
+		if err != nil {
+			g.Logger.Error("session exists", err)
+		}
+
+		if err == nil && !exists {
+			return handlerErr
+		}
 	}
 
+	// Applies the configured expiration to the sessions
+	// created before it was reloaded as well.
+	sess.SetExpiry(g.Settings.Load().SessionExpiration)
+
+	// The index of the sessions of the user lives as long as
+	// the last session used, so it's kept alive along with it.
+	isLoggedIn, _ = sess.Get(IsLoggedInKey).(bool)
+	userId, _ := sess.Get(UserIdKey).(int)
+
 	err = sess.Save()
 	if err != nil {
 		g.Logger.Error("session save", err)
 		panic(err)
 	}
 
-	return nil
+	if isLoggedIn {
+		err = g.Sessions.Touch(userId)
+		if err != nil {
+			g.Logger.Error("session index touch", err, "userId", userId)
+		}
+	}
+
+	return handlerErr
 }
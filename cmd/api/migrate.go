@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+const migrateUsage = `Usage: api migrate <up|down|status> [-config=<path/to/config/file>] [-set=<key>=<value>...]
+
+	up      applies the pending migrations
+	down    reverts the last applied migration
+	status  lists the migrations and whether they're applied`
+
+// Runs the subcommand `migrate`, which manages the migrations of
+// the schema of the configured database. It returns the exit code.
+func RunMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		return 2
+	}
+
+	action := args[0]
+
+	flagSet := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags := DefineConfigFlags(flagSet)
+	flagSet.Parse(args[1:])
+
+	config := MustLoadConfig(*flags)
+
+	if config.Database.Driver == DriverMemory {
+		fmt.Println("The in-memory database has no schema to migrate")
+		return 0
+	}
+
+	sqldb, migrator, err := OpenSQLDatabase(config.Database)
+	if err != nil {
+		fmt.Println("An error occured while connecting to the database:")
+		fmt.Println()
+
+		fmt.Println(err)
+
+		fmt.Println()
+		return 1
+	}
+	defer sqldb.Close()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		if len(applied) == 0 {
+			fmt.Printf("schema is up to date (version %d)\n", migrator.Latest())
+		}
+
+	case "down":
+		migration, err := migrator.Down()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		fmt.Println(migrateUsage)
+		return 2
+	}
+
+	return 0
+}
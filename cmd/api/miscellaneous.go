@@ -3,25 +3,55 @@ package main
 import (
 	"encoding/json"
 	"errors"
-	"flag"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"path"
-	"strings"
-
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/password"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/validator"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func (g *Global) GetSession(c *fiber.Ctx) *session.Session {
 	return c.Locals(SessionKey).(*session.Session)
 }
 
-// Helper function to create server errors.
-func (g *Global) ServerError(c *fiber.Ctx, err error) error {
-	return SendErrorMessage(c, fiber.StatusInternalServerError, ErrServerInternal, err)
+// Returns the id of the logged-in user, it must only be
+// called from handlers behind the `RequireAuth` middleware.
+func (g *Global) GetUserId(c *fiber.Ctx) int {
+	// Users authenticated with a bearer token.
+	if id, ok := c.Locals(TokenUserIdKey).(int); ok {
+		return id
+	}
+
+	id, _ := g.GetSession(c).Get(UserIdKey).(int)
+	return id
+}
+
+// Returns the id of the refresh token issued along with the access
+// token of the request, it's empty for cookie based sessions.
+func (g *Global) GetTokenId(c *fiber.Ctx) string {
+	id, _ := c.Locals(TokenIdKey).(string)
+	return id
+}
+
+// Returns the id of the login of the request, which is the id of
+// the refresh token for the requests authenticated with a bearer
+// token and the id of the session otherwise.
+func (g *Global) GetLoginId(c *fiber.Ctx) string {
+	if id := g.GetTokenId(c); id != "" {
+		return id
+	}
+
+	return g.GetSession(c).ID()
 }
 
 // Helper function to create error response message.
@@ -33,6 +63,54 @@ func SendErrorMessage[T any](c *fiber.Ctx, status int, err error, details T) err
 	})
 }
 
+// Helper function to create the error of a payload that
+// failed validation, the details list every field error.
+func ValidationError(v *validator.Validator) error {
+	return codes.WithDetails(ErrValidationFailed, v.Errors())
+}
+
+// Helper function to create the error of a password that breaks
+// the rules of the policy, the details list every rule with its
+// message in the language of the user.
+func (g *Global) PasswordError(c *fiber.Ctx, rules []string) error {
+	language := g.Language(c)
+
+	params := i18n.Params{
+		"min":      g.PasswordPolicy.MinLength,
+		"max":      g.PasswordPolicy.MaxLength,
+		"maxBytes": g.PasswordPolicy.MaxBytes(),
+	}
+
+	violations := make([]password.Violation, len(rules))
+
+	for i, rule := range rules {
+		violations[i] = password.Violation{
+			Rule:    rule,
+			Message: g.Messages.Message(language, "password."+rule, params),
+		}
+	}
+
+	return codes.WithDetails(ErrPasswordNotValid, violations)
+}
+
+// Returns the language of the messages sent to the user, the
+// language chosen by the user (see `UserLanguage`) takes
+// precedence over the header `Accept-Language`.
+func (g *Global) Language(c *fiber.Ctx) string {
+	// Set by `ManageSession` and `UserLanguage`.
+	language, ok := c.Locals(LanguageKey).(string)
+	if ok && g.Messages.Has(language) {
+		return language
+	}
+
+	return g.Messages.Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+}
+
+// Returns the message identified by key in the language of the user.
+func (g *Global) Message(c *fiber.Ctx, key string, params i18n.Params) string {
+	return g.Messages.Message(g.Language(c), key, params)
+}
+
 // Helper function to create response sucess message.
 func SendSucessMessage[T any](c *fiber.Ctx, status int, data T) error {
 	return c.Status(status).JSON(SuccessMessage[T]{
@@ -47,7 +125,7 @@ func ReadBodyFromRequest[T any](c *fiber.Ctx) (T, error) {
 
 	err := c.BodyParser(&v)
 	if err != nil {
-		return v, err
+		return v, codes.WithDetails(ErrCannotDecodeJSON, err.Error())
 	}
 
 	return v, nil
@@ -59,75 +137,54 @@ func ReadJSONBody[T any](body []byte) (T, error) {
 
 	err := json.Unmarshal(body, &v)
 	if err != nil {
-		return v, err
+		return v, codes.WithDetails(ErrCannotDecodeJSON, err.Error())
 	}
 
 	return v, nil
 }
 
-func ValidatePassword(password string) error {
-	// Trim leading and trailing whitespace.
-	password = strings.Trim(password, " ")
-
-	var err error
-
-	// Must have 8 character or more.
-	if len(password) <= 8 {
-		err = ErrPasswordNotValid
-	}
-
-	// Must have at least one digit.
-	if !strings.ContainsAny(password, "0123456789") {
-		err = ErrPasswordNotValid
-	}
-
-	// Must have at least one special character.
-	if !strings.ContainsAny(password, "@$#") {
-		err = ErrPasswordNotValid
-	}
-
-	return err
-}
-
-// Reads the JSON configuration file from the provided
-// file system path, then parses the configuration into
-// the `Config` struct, and finally returns it with a
-// nill error if everything goes fine. Otherwise, it returns
-// a non-nil error, which should be checked.
-func LoadConfig(path string) (Config, error) {
-	fd, err := os.Open(path)
-	if err != nil {
-		return Config{}, err
-	}
-	defer fd.Close()
-
-	decoder := json.NewDecoder(fd)
-	decoder.DisallowUnknownFields()
-
+// Returns the configuration with the default values
+// of the fields that can be omitted.
+func DefaultConfig() Config {
 	var config Config
 
-	err = decoder.Decode(&config)
-	if err != nil {
-		return Config{}, err
+	config.Database.Driver = DriverSQLServer
+	config.Server.ShutdownTimeout = 30
+	config.Server.BodyLimit = fiber.DefaultBodyLimit
+	config.Match.Duration = 300
+	config.Match.Timeout = 25
+	config.User.DeletionPolicy = models.DeletionPolicyTombstone
+	config.Auth.AccessTokenTTL = 15 * 60
+	config.Auth.RefreshTokenTTL = 30 * 24 * 60 * 60
+	config.Login.FreeAttempts = 3
+	config.Login.MaxAttempts = 10
+	config.Login.BaseDelay = 1
+	config.Login.Lockout = 15 * 60
+	config.Password = password.DefaultPolicy()
+	config.Hashing.Algorithm = hashing.AlgorithmBcrypt
+	config.Hashing.BcryptCost = hashing.BcryptDefaultCost
+	config.Hashing.Argon2id.Time = hashing.Argon2idDefaultTime
+	config.Hashing.Argon2id.Memory = hashing.Argon2idDefaultMemory
+	config.Hashing.Argon2id.Threads = hashing.Argon2idDefaultThreads
+	config.RateLimit = map[string]RateLimitRule{
+		RateLimitGroupUser:          {Limit: 60, Window: 60},
+		RateLimitGroupAuth:          {Limit: 30, Window: 60},
+		RateLimitGroupConversations: {Limit: 120, Window: 60},
+		RateLimitGroupMatch:         {Limit: 30, Window: 60},
 	}
-
-	return config, nil
+	config.Log.Level = "info"
+	config.Session.Expiration = int(SessionExpiration / time.Second)
+	config.Images.MaxSize = 1024 * 1024 * 3 / 2 // 1.5MB
+	config.Health.Timeout = 2
+	config.Health.CacheTTL = 5
+	config.Health.MinFreeDisk = 100 * 1024 * 1024 // 100MB
+
+	return config
 }
 
-// Defines, parses and returns the command line flags.
-func GetFlags() Flags {
-	config := flag.String("config", "", "The path of the configuration file")
-
-	flag.Parse()
-
-	if *config == "" {
-		log.Fatalln("The flag [config] is required")
-	}
-
-	return Flags{
-		ConfigPath: *config,
-	}
-}
+// Room in bytes left in the body limit for the multipart form wrapping
+// the profile pictures (boundaries, headers and the other fields).
+const MultipartOverhead = 64 * 1024
 
 // Validates the configuration, returns a slice of strings
 // with the errors. If none were found, the return slice
@@ -145,11 +202,27 @@ func ValidateConfig(config Config) []string {
 		}
 	}
 
-	databaseErrors := ValidateConnectionDetails(database)
-	if databaseErrors != nil {
-		for _, err := range databaseErrors {
-			validationsErrors = append(validationsErrors, fmt.Sprintf("database: %s", err))
+	switch database.Driver {
+	case DriverSQLServer:
+		databaseErrors := ValidateConnectionDetails(database.ConnectionDetails)
+		if databaseErrors != nil {
+			for _, err := range databaseErrors {
+				validationsErrors = append(validationsErrors, fmt.Sprintf("database: %s", err))
+			}
+		}
+
+	case DriverSQLite:
+		if database.Path == "" {
+			validationsErrors = append(validationsErrors, "database: field required: path")
 		}
+
+	case DriverMemory:
+
+	default:
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("database: unknown driver %q", database.Driver),
+		)
 	}
 
 	addrError := ValidateAddr(config.Server.Addr)
@@ -157,10 +230,160 @@ func ValidateConfig(config Config) []string {
 		validationsErrors = append(validationsErrors, fmt.Sprintf("server: %s", addrError.Error()))
 	}
 
+	if config.Server.ShutdownTimeout <= 0 {
+		validationsErrors = append(validationsErrors, "server: shutdownTimeout must be greater than 0")
+	}
+
+	if config.Server.BodyLimit <= 0 {
+		validationsErrors = append(validationsErrors, "server: bodyLimit must be greater than 0")
+	}
+
+	// Otherwise the clients could set their address.
+	if config.Server.ProxyHeader != "" && len(config.Server.TrustedProxies) == 0 {
+		validationsErrors = append(validationsErrors, "server: trustedProxies required with proxyHeader")
+	}
+
+	for _, proxy := range config.Server.TrustedProxies {
+		if !isIPOrCIDR(proxy) {
+			validationsErrors = append(
+				validationsErrors,
+				fmt.Sprintf("server: trusted proxy %q is not an IP address or a CIDR range", proxy),
+			)
+		}
+	}
+
 	if config.AppData == "" {
 		validationsErrors = append(validationsErrors, "field required: appdata")
 	}
 
+	if config.Match.Duration <= 0 || config.Match.Duration > models.ConversationDurationMax {
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("match: duration must be between 1 and %d", models.ConversationDurationMax),
+		)
+	}
+
+	if config.Match.Timeout <= 0 {
+		validationsErrors = append(validationsErrors, "match: timeout must be greater than 0")
+	}
+
+	if !config.User.DeletionPolicy.IsValid() {
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("user: unknown deletion policy %q", config.User.DeletionPolicy),
+		)
+	}
+
+	if config.Auth.Secret != "" && len(config.Auth.Secret) < AuthSecretMinLength {
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("auth: secret must have %d characters or more", AuthSecretMinLength),
+		)
+	}
+
+	if config.Auth.AccessTokenTTL <= 0 {
+		validationsErrors = append(validationsErrors, "auth: accessTokenTtl must be greater than 0")
+	}
+
+	if config.Auth.RefreshTokenTTL <= 0 {
+		validationsErrors = append(validationsErrors, "auth: refreshTokenTtl must be greater than 0")
+	}
+
+	if config.Login.FreeAttempts < 0 {
+		validationsErrors = append(validationsErrors, "login: freeAttempts must not be negative")
+	}
+
+	if config.Login.MaxAttempts <= config.Login.FreeAttempts {
+		validationsErrors = append(validationsErrors, "login: maxAttempts must be greater than freeAttempts")
+	}
+
+	if config.Login.BaseDelay <= 0 {
+		validationsErrors = append(validationsErrors, "login: baseDelay must be greater than 0")
+	}
+
+	if config.Login.Lockout <= 0 {
+		validationsErrors = append(validationsErrors, "login: lockout must be greater than 0")
+	}
+
+	if config.Password.MinLength <= 0 {
+		validationsErrors = append(validationsErrors, "password: minLength must be greater than 0")
+	}
+
+	if config.Password.MaxLength < config.Password.MinLength {
+		validationsErrors = append(validationsErrors, "password: maxLength must not be less than minLength")
+	}
+
+	switch config.Hashing.Algorithm {
+	case hashing.AlgorithmBcrypt, hashing.AlgorithmArgon2id:
+	default:
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("hashing: unknown algorithm %q", config.Hashing.Algorithm),
+		)
+	}
+
+	if config.Hashing.BcryptCost < bcrypt.MinCost || config.Hashing.BcryptCost > bcrypt.MaxCost {
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("hashing: bcryptCost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost),
+		)
+	}
+
+	if config.Hashing.Argon2id.Time <= 0 {
+		validationsErrors = append(validationsErrors, "hashing: argon2id: time must be greater than 0")
+	}
+
+	if config.Hashing.Argon2id.Memory < 8*config.Hashing.Argon2id.Threads {
+		validationsErrors = append(validationsErrors, "hashing: argon2id: memory must be at least 8 KiB per thread")
+	}
+
+	if config.Hashing.Argon2id.Threads <= 0 || config.Hashing.Argon2id.Threads > 255 {
+		validationsErrors = append(validationsErrors, "hashing: argon2id: threads must be between 1 and 255")
+	}
+
+	for group, rule := range config.RateLimit {
+		if !IsRateLimitGroup(group) {
+			validationsErrors = append(validationsErrors, fmt.Sprintf("rateLimit: unknown group %q", group))
+			continue
+		}
+
+		if rule.Limit < 0 {
+			validationsErrors = append(validationsErrors, fmt.Sprintf("rateLimit: %s: limit must not be negative", group))
+		}
+
+		if rule.Window <= 0 {
+			validationsErrors = append(validationsErrors, fmt.Sprintf("rateLimit: %s: window must be greater than 0", group))
+		}
+	}
+
+	if _, ok := LogLevels[config.Log.Level]; !ok {
+		validationsErrors = append(validationsErrors, fmt.Sprintf("log: unknown level %q", config.Log.Level))
+	}
+
+	if config.Session.Expiration <= 0 {
+		validationsErrors = append(validationsErrors, "session: expiration must be greater than 0")
+	}
+
+	if config.Images.MaxSize <= 0 {
+		validationsErrors = append(validationsErrors, "images: maxSize must be greater than 0")
+	}
+
+	// Otherwise the pictures are rejected before reaching the handler.
+	if config.Images.MaxSize+MultipartOverhead > int64(config.Server.BodyLimit) {
+		validationsErrors = append(
+			validationsErrors,
+			fmt.Sprintf("images: maxSize plus %d bytes of multipart overhead must not be greater than server.bodyLimit", MultipartOverhead),
+		)
+	}
+
+	if config.Health.Timeout <= 0 {
+		validationsErrors = append(validationsErrors, "health: timeout must be greater than 0")
+	}
+
+	if config.Health.CacheTTL < 0 {
+		validationsErrors = append(validationsErrors, "health: cacheTtl must not be negative")
+	}
+
 	return validationsErrors
 }
 
@@ -194,6 +417,16 @@ func ValidateAddr(addr string) error {
 	return err
 }
 
+// Reports whether value is an IP address or a CIDR range.
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
 // Create the dirs where data generated by the
 // application will be stored.
 func CreateAppDataDirs(appDataPath string) error {
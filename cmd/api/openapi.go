@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Edwing123/udem-chat-app/pkg/health"
+	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/openapi"
+	"github.com/Edwing123/udem-chat-app/pkg/tokens"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Content types of the documented routes.
+const (
+	contentJSON      = fiber.MIMEApplicationJSON
+	contentForm      = fiber.MIMEMultipartForm
+	contentText      = fiber.MIMETextPlain
+	contentImage     = "image/*"
+	contentWebSocket = "websocket"
+)
+
+// routeDoc documents a route, the values of the bodies
+// are only used for their types (see `openapi.SchemaOf`).
+type routeDoc struct {
+	Summary string
+
+	// Group of the route, see `routeTag` for the default.
+	Tag string
+
+	// Whether the route is behind the `RequireAuth` middleware.
+	Auth bool
+
+	// Body of the request, nil if the route has no body.
+	Request any
+
+	// Content type of the request body, JSON by default.
+	RequestType string
+
+	// Query parameters, the values are used for their types.
+	Query map[string]any
+
+	// Status and data of the successful responses, the data is
+	// wrapped in `SuccessMessage` for the JSON responses.
+	Status   int
+	Response any
+
+	// Content type of the successful responses, empty for
+	// the JSON responses wrapped in `SuccessMessage`.
+	ResponseType string
+}
+
+// UserUpdateForm represents the form of the request for updating
+// the user, it's only used for documenting the route.
+type UserUpdateForm struct {
+	Name      string `json:"name,omitempty"`
+	Birthdate string `json:"birthdate,omitempty"`
+
+	// The image, either JPEG, WebP or PNG.
+	ProfilePicture []byte `json:"profilePicture,omitempty"`
+
+	// Crop of the image encoded in JSON.
+	Crop profile.Crop `json:"crop,omitempty"`
+}
+
+// Documentation of the routes registered by `Global.Setup`
+// by method and path, every route must be documented.
+var routeDocs = map[string]routeDoc{
+	"GET /images/profile/:id<guid>": {
+		Summary:      "Get a profile picture",
+		Status:       fiber.StatusOK,
+		ResponseType: contentImage,
+	},
+	"GET /ws": {
+		Summary:      "Open the WebSocket connection of the real-time events",
+		Auth:         true,
+		Status:       fiber.StatusSwitchingProtocols,
+		ResponseType: contentWebSocket,
+	},
+	"GET /api/openapi.json": {
+		Summary:      "Get the OpenAPI document of the API",
+		Status:       fiber.StatusOK,
+		ResponseType: contentJSON,
+	},
+	"GET /healthz": {
+		Summary:  "Check whether the server is alive",
+		Tag:      "health",
+		Status:   fiber.StatusOK,
+		Response: health.StatusOk,
+	},
+	"GET /readyz": {
+		Summary:  "Check whether the dependencies of the server are usable",
+		Tag:      "health",
+		Status:   fiber.StatusOK,
+		Response: health.Report{},
+	},
+	"GET /metrics": {
+		Summary:      "Get the metrics in the Prometheus text format",
+		Status:       fiber.StatusOK,
+		ResponseType: contentText,
+	},
+	"GET /api/hello": {
+		Summary:      "Count the visits of the session",
+		Status:       fiber.StatusOK,
+		ResponseType: contentText,
+	},
+
+	"POST /api/user/login": {
+		Summary:  "Log in with a session cookie",
+		Request:  models.User{},
+		Status:   fiber.StatusOK,
+		Response: fiber.Map{},
+	},
+	"POST /api/user/signup": {
+		Summary:  "Sign up",
+		Request:  models.User{},
+		Status:   fiber.StatusCreated,
+		Response: "",
+	},
+	"POST /api/user/logout": {
+		Summary:  "Log out",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"GET /api/user/status": {
+		Summary:  "Get whether the session is logged in",
+		Status:   fiber.StatusOK,
+		Response: fiber.Map{},
+	},
+	"PUT /api/user/language": {
+		Summary:  "Choose the language of the messages",
+		Request:  LanguageRequest{},
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"PATCH /api/user/update": {
+		Summary:     "Update the logged-in user",
+		Auth:        true,
+		Request:     UserUpdateForm{},
+		RequestType: contentForm,
+		Status:      fiber.StatusOK,
+		Response:    models.User{},
+	},
+	"POST /api/user/password": {
+		Summary:  "Change the password of the logged-in user",
+		Auth:     true,
+		Request:  ChangePasswordRequest{},
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"DELETE /api/user": {
+		Summary:  "Delete the account of the logged-in user",
+		Auth:     true,
+		Request:  DeleteUserRequest{},
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"GET /api/user/sessions": {
+		Summary:  "List the sessions of the logged-in user",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: []SessionInfo{},
+	},
+	"DELETE /api/user/sessions": {
+		Summary:  "Close every session of the logged-in user",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"DELETE /api/user/sessions/:id": {
+		Summary:  "Close a session of the logged-in user",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"GET /api/user/data": {
+		Summary:  "Get the logged-in user",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: models.User{},
+	},
+
+	"POST /api/auth/token": {
+		Summary:  "Log in with bearer tokens",
+		Request:  models.User{},
+		Status:   fiber.StatusOK,
+		Response: tokens.Pair{},
+	},
+	"POST /api/auth/refresh": {
+		Summary:  "Refresh the bearer tokens",
+		Request:  RefreshTokenRequest{},
+		Status:   fiber.StatusOK,
+		Response: tokens.Pair{},
+	},
+	"POST /api/auth/revoke": {
+		Summary:  "Revoke a refresh token",
+		Request:  RefreshTokenRequest{},
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+
+	"POST /api/conversations": {
+		Summary:  "Create a conversation",
+		Auth:     true,
+		Request:  NewConversationRequest{},
+		Status:   fiber.StatusCreated,
+		Response: models.Conversation{},
+	},
+	"GET /api/conversations": {
+		Summary:  "List the conversations of the logged-in user",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: []models.Conversation{},
+	},
+	"GET /api/conversations/:id<int>": {
+		Summary:  "Get a conversation",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: models.Conversation{},
+	},
+	"POST /api/conversations/:id<int>/participants": {
+		Summary:  "Add a participant to a conversation",
+		Auth:     true,
+		Request:  ParticipantRequest{},
+		Status:   fiber.StatusCreated,
+		Response: "",
+	},
+	"DELETE /api/conversations/:id<int>/participants/:userId<int>": {
+		Summary:  "Remove a participant from a conversation",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"POST /api/conversations/:id<int>/end": {
+		Summary:  "End a conversation",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+	"POST /api/conversations/:id<int>/messages": {
+		Summary:  "Send a message",
+		Auth:     true,
+		Request:  NewMessageRequest{},
+		Status:   fiber.StatusCreated,
+		Response: models.Message{},
+	},
+	"GET /api/conversations/:id<int>/messages": {
+		Summary: "Get a page of the messages of a conversation",
+		Auth:    true,
+		Query: map[string]any{
+			"before": "",
+			"after":  "",
+			"limit":  0,
+		},
+		Status:   fiber.StatusOK,
+		Response: models.MessagesPage{},
+	},
+	"GET /api/conversations/:id<int>/messages/:messageId<int>": {
+		Summary:  "Get a message",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: models.Message{},
+	},
+
+	"POST /api/match": {
+		Summary:  "Wait for a partner",
+		Auth:     true,
+		Status:   fiber.StatusCreated,
+		Response: models.Conversation{},
+	},
+	"DELETE /api/match": {
+		Summary:  "Stop waiting for a partner",
+		Auth:     true,
+		Status:   fiber.StatusOK,
+		Response: "",
+	},
+}
+
+// Generates the OpenAPI document of the routes, it fails
+// if any route is missing from `routeDocs`, the document
+// is generated anyway with the documented routes.
+func NewOpenAPI(routes []fiber.Route) (*openapi.Document, error) {
+	document := openapi.New(openapi.Info{
+		Title:       "Nameless",
+		Description: "See docs/API.md for the details of the routes.",
+		Version:     "1.0.0",
+	})
+
+	document.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"session": {Type: "apiKey", In: "cookie", Name: "session_id"},
+		"bearer":  {Type: "http", Scheme: "bearer"},
+	}
+
+	// Shared by every route, the generic types are described in place.
+	document.Components.Schemas["ErrorMessage"] = document.SchemaOf(ErrorMessage[any]{})
+
+	errorResponse := openapi.Response{
+		Description: "Error, see the code in `err`",
+		Content: map[string]openapi.MediaType{
+			contentJSON: {Schema: &openapi.Schema{Ref: "#/components/schemas/ErrorMessage"}},
+		},
+	}
+
+	var undocumented []string
+
+	for _, route := range routes {
+		// Registered by Fiber along with every GET route.
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+
+		doc, ok := routeDocs[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+
+		path, parameters := openapi.Path(route.Path)
+
+		tag := doc.Tag
+		if tag == "" {
+			tag = routeTag(route.Path)
+		}
+
+		operation := &openapi.Operation{
+			Tags:       []string{tag},
+			Summary:    doc.Summary,
+			Parameters: append(parameters, queryParameters(document, doc.Query)...),
+			Responses: map[string]openapi.Response{
+				strconv.Itoa(doc.Status): successResponse(document, doc),
+				"default":                errorResponse,
+			},
+		}
+
+		if doc.Auth {
+			operation.Security = []map[string][]string{
+				{"session": {}},
+				{"bearer": {}},
+			}
+		}
+
+		if doc.Request != nil {
+			requestType := doc.RequestType
+			if requestType == "" {
+				requestType = contentJSON
+			}
+
+			operation.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content: map[string]openapi.MediaType{
+					requestType: {Schema: document.SchemaOf(doc.Request)},
+				},
+			}
+		}
+
+		document.AddOperation(path, route.Method, operation)
+	}
+
+	if len(undocumented) > 0 {
+		return document, fmt.Errorf("undocumented routes: %s", strings.Join(undocumented, ", "))
+	}
+
+	return document, nil
+}
+
+// Returns the group of the route (`user` for `/api/user/login`).
+func routeTag(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	if segments[0] == "api" && len(segments) > 1 {
+		return segments[1]
+	}
+
+	return segments[0]
+}
+
+func successResponse(document *openapi.Document, doc routeDoc) openapi.Response {
+	response := openapi.Response{
+		Description: "Success",
+	}
+
+	switch doc.ResponseType {
+	case "":
+		response.Content = map[string]openapi.MediaType{
+			contentJSON: {Schema: document.SchemaOf(SuccessMessage[any]{})},
+		}
+
+		response.Content[contentJSON].Schema.Properties["data"] = document.SchemaOf(doc.Response)
+
+	case contentWebSocket:
+
+	default:
+		response.Content = map[string]openapi.MediaType{
+			doc.ResponseType: {},
+		}
+	}
+
+	return response
+}
+
+func queryParameters(document *openapi.Document, query map[string]any) []openapi.Parameter {
+	names := make([]string, 0, len(query))
+
+	for name := range query {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var parameters []openapi.Parameter
+
+	for _, name := range names {
+		parameters = append(parameters, openapi.Parameter{
+			Name:   name,
+			In:     "query",
+			Schema: document.SchemaOf(query[name]),
+		})
+	}
+
+	return parameters
+}
+
+// Encodes the document the way it's served and checked in.
+func EncodeOpenAPI(document *openapi.Document) ([]byte, error) {
+	data, err := json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
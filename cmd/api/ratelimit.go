@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route groups that can be rate limited, they're
+// the keys of the `rateLimit` configuration option.
+const (
+	RateLimitGroupUser          = "user"
+	RateLimitGroupAuth          = "auth"
+	RateLimitGroupConversations = "conversations"
+	RateLimitGroupMatch         = "match"
+)
+
+// Reports whether group is one of the route groups that can be rate limited.
+func IsRateLimitGroup(group string) bool {
+	switch group {
+	case RateLimitGroupUser, RateLimitGroupAuth, RateLimitGroupConversations, RateLimitGroupMatch:
+		return true
+	}
+
+	return false
+}
+
+// Returns the rules of the rate limits of the configuration,
+// the disabled limits (zero requests) are left out.
+func NewRateLimits(config Config) map[string]ratelimit.Rule {
+	rules := map[string]ratelimit.Rule{}
+
+	for group, rule := range config.RateLimit {
+		if rule.Limit == 0 {
+			continue
+		}
+
+		rules[group] = ratelimit.Rule{
+			Limit:  rule.Limit,
+			Window: time.Duration(rule.Window) * time.Second,
+		}
+	}
+
+	return rules
+}
+
+// Returns the key identifying the client of the request for the rate
+// limits, the id of the user if logged in and the IP address otherwise.
+func (g *Global) rateLimitKey(c *fiber.Ctx) string {
+	id := g.GetUserId(c)
+
+	// The access token is only verified by `RequireAuth`,
+	// which may not have run yet.
+	if accessToken, ok := bearerToken(c.Get(fiber.HeaderAuthorization)); id == 0 && ok {
+		id, _, _ = g.Tokens.Verify(accessToken)
+	}
+
+	if id != 0 {
+		return fmt.Sprintf("user_%d", id)
+	}
+
+	return "ip_" + c.IP()
+}
+
+// Creates the middleware limiting the requests to the route group,
+// the state of the limit is sent in the `RateLimit-*` headers.
+func (g *Global) RateLimit(group string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rule, ok := g.Settings.Load().RateLimits[group]
+		if !ok {
+			return c.Next()
+		}
+
+		result := g.RateLimiter.Take(group+"_"+g.rateLimitKey(c), rule)
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, reset)
+
+			return ratelimit.ErrRateLimited
+		}
+
+		return c.Next()
+	}
+}
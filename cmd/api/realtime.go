@@ -0,0 +1,12 @@
+package main
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler for opening the WebSocket connection through which
+// the events of the conversations of the logged-in user are pushed,
+// the connection is closed once its login is revoked.
+func (g *Global) RealtimeConnect(c *fiber.Ctx) error {
+	return g.Hub.Upgrade(c, g.GetUserId(c), g.GetLoginId(c))
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/ratelimit"
+	"golang.org/x/exp/slog"
+)
+
+// Sections of the configuration that can be reloaded while
+// the server runs, the other ones need a restart.
+var ReloadableSections = []string{"log", "session", "images", "rateLimit"}
+
+// Settings are the options of the reloadable sections of the
+// configuration used by the handlers, see `Global.ApplySettings`.
+type Settings struct {
+	// Rate limits by route group.
+	RateLimits map[string]ratelimit.Rule
+
+	// Time a session lives without being used.
+	SessionExpiration time.Duration
+
+	// Maximum size in bytes of the uploaded profile pictures.
+	MaxImageSize int64
+}
+
+// Returns the settings of the configuration.
+func NewSettings(config Config) *Settings {
+	return &Settings{
+		RateLimits:        NewRateLimits(config),
+		SessionExpiration: time.Duration(config.Session.Expiration) * time.Second,
+		MaxImageSize:      config.Images.MaxSize,
+	}
+}
+
+// Applies the reloadable options of the configuration, the
+// requests already running keep the previous settings.
+func (g *Global) ApplySettings(config Config) {
+	settings := NewSettings(config)
+
+	g.Settings.Store(settings)
+	g.Sessions.SetExpiration(settings.SessionExpiration)
+	g.LogLevel.Set(LogLevels[config.Log.Level])
+}
+
+// Reloader reloads the configuration of the running server.
+type Reloader struct {
+	// Serializes the reloads.
+	mu sync.Mutex
+
+	// The configuration in use, only its reloadable
+	// sections are replaced by the reloads.
+	config Config
+
+	flags  Flags
+	global *Global
+	logger *slog.Logger
+}
+
+// Creates a reloader of the configuration loaded from the
+// flags, config is the configuration the server started with.
+func NewReloader(global *Global, flags Flags, config Config) *Reloader {
+	return &Reloader{
+		config: config,
+		flags:  flags,
+		global: global,
+		logger: global.Logger,
+	}
+}
+
+// Reloads the configuration on SIGHUP until the returned function is called.
+func (r *Reloader) Watch() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				r.Reload()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// Loads and validates the configuration again, then applies its
+// reloadable sections, every changed option is logged. The
+// configurations that can't be loaded or are not valid are
+// rejected, the running server keeps its settings.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, err := LoadConfig(r.flags, os.Environ())
+	if err != nil {
+		r.logger.Error("config reload rejected", err)
+		return err
+	}
+
+	validationErrors := ValidateConfig(config)
+	if validationErrors != nil {
+		err := fmt.Errorf("config not valid: %s", strings.Join(validationErrors, "; "))
+		r.logger.Error("config reload rejected", err)
+		return err
+	}
+
+	// The body limit needs a restart, so the pictures
+	// must fit the one the server started with.
+	if config.Images.MaxSize+MultipartOverhead > int64(r.config.Server.BodyLimit) {
+		err := fmt.Errorf(
+			"config not valid: images: maxSize plus %d bytes of multipart overhead must not be greater than server.bodyLimit (%d) until restart",
+			MultipartOverhead,
+			r.config.Server.BodyLimit,
+		)
+		r.logger.Error("config reload rejected", err)
+		return err
+	}
+
+	// The secrets are not logged.
+	oldValues := flattenConfig(RedactConfig(r.config))
+	newValues := flattenConfig(RedactConfig(config))
+
+	var restartKeys []string
+
+	for _, key := range DiffConfig(r.config, config) {
+		if !IsReloadableKey(key) {
+			restartKeys = append(restartKeys, key)
+			continue
+		}
+
+		r.logger.Info("config reloaded", "key", key, "old", oldValues[key], "new", newValues[key])
+	}
+
+	if len(restartKeys) > 0 {
+		r.logger.Warn("config changes ignored until restart", "keys", strings.Join(restartKeys, ","))
+	}
+
+	r.global.ApplySettings(config)
+
+	r.config.Log = config.Log
+	r.config.Session = config.Session
+	r.config.Images = config.Images
+	r.config.RateLimit = config.RateLimit
+
+	return nil
+}
+
+// Reports whether the option identified by key can be reloaded.
+func IsReloadableKey(key string) bool {
+	for _, section := range ReloadableSections {
+		if strings.HasPrefix(key, section+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the keys of the options whose values differ
+// between the configurations, sorted alphabetically.
+func DiffConfig(a Config, b Config) []string {
+	aValues := flattenConfig(a)
+	bValues := flattenConfig(b)
+
+	var keys []string
+
+	for key, value := range aValues {
+		if bValue, ok := bValues[key]; !ok || bValue != value {
+			keys = append(keys, key)
+		}
+	}
+
+	for key := range bValues {
+		if _, ok := aValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Returns the values of the options of the configuration
+// by key (see `ConfigOverrides`), formatted as text.
+func flattenConfig(config Config) map[string]string {
+	values := map[string]string{}
+	flattenValue(reflect.ValueOf(config), "", values)
+
+	return values
+}
+
+func flattenValue(v reflect.Value, key string, values map[string]string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+
+			if !field.IsExported() {
+				continue
+			}
+
+			if isPromoted(field) {
+				flattenValue(v.Field(i), key, values)
+				continue
+			}
+
+			flattenValue(v.Field(i), joinConfigKey(key, jsonFieldName(field)), values)
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			flattenValue(iter.Value(), joinConfigKey(key, fmt.Sprint(iter.Key())), values)
+		}
+
+	default:
+		values[key] = fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	writeConfig := func(content string) {
+		err := os.WriteFile(path, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig(`{
+		"redis": {"user": "nameless", "password": "secret", "host": "localhost"},
+		"database": {"driver": "memory"},
+		"server": {"addr": ":8080"},
+		"appdata": "./data"
+	}`)
+
+	flags := Flags{ConfigPath: path}
+
+	config, err := LoadConfig(flags, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+
+	g := &Global{
+		Logger:   NewLogger(&logs, slog.InfoLevel),
+		Sessions: NewSessionIndex(NewSessionStore(nil).Storage),
+		LogLevel: new(slog.LevelVar),
+	}
+
+	g.ApplySettings(config)
+	reloader := NewReloader(g, flags, config)
+
+	// The reloadable sections are applied, the
+	// other changes need a restart.
+	writeConfig(`{
+		"redis": {"user": "nameless", "password": "changed", "host": "localhost"},
+		"database": {"driver": "memory"},
+		"server": {"addr": ":9090"},
+		"appdata": "./data",
+		"log": {"level": "debug"},
+		"session": {"expiration": 60},
+		"rateLimit": {"user": {"limit": 5, "window": 10}}
+	}`)
+
+	err = reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := g.Settings.Load()
+
+	if rule := settings.RateLimits[RateLimitGroupUser]; rule.Limit != 5 || rule.Window != 10*time.Second {
+		t.Errorf("expected the reloaded rate limit, got %+v", rule)
+	}
+
+	if settings.SessionExpiration != time.Minute || g.LogLevel.Level() != slog.DebugLevel {
+		t.Errorf("expected the reloaded settings, got %+v and level %s", settings, g.LogLevel.Level())
+	}
+
+	output := logs.String()
+
+	if !strings.Contains(output, "key=log.level old=info new=debug") {
+		t.Errorf("expected the changes logged, got %q", output)
+	}
+
+	if !strings.Contains(output, "keys=redis.password,server.addr") || strings.Contains(output, "changed") {
+		t.Errorf("expected the restart needed logged without secrets, got %q", output)
+	}
+
+	// The configurations not valid are rejected.
+	writeConfig(`{"session": {"expiration": 0}}`)
+
+	err = reloader.Reload()
+	if err == nil {
+		t.Fatal("expected an error for the config not valid")
+	}
+
+	if g.Settings.Load() != settings {
+		t.Error("expected the settings not changed by the rejected reload")
+	}
+
+	// The pictures must fit the body limit the server started with.
+	writeConfig(`{
+		"redis": {"user": "nameless", "password": "secret", "host": "localhost"},
+		"database": {"driver": "memory"},
+		"server": {"addr": ":8080", "bodyLimit": 16777216},
+		"appdata": "./data",
+		"images": {"maxSize": 8388608}
+	}`)
+
+	err = reloader.Reload()
+	if err == nil {
+		t.Fatal("expected an error for the images bigger than the body limit")
+	}
+
+	if g.Settings.Load() != settings {
+		t.Error("expected the settings not changed by the rejected reload")
+	}
+}
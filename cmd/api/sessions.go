@@ -1,6 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -8,12 +17,16 @@ import (
 	"github.com/gofiber/storage/redis"
 )
 
+// Time a session lives without being used by default,
+// see `Config.Session`.
+const SessionExpiration = time.Hour * 1
+
 // Creates a Fiber store for keeping track of
 // sessions data.
 func NewSessionStore(storage fiber.Storage) *session.Store {
 	store := session.New(session.Config{
 		Storage:        storage,
-		Expiration:     time.Hour * 1,
+		Expiration:     SessionExpiration,
 		CookieHTTPOnly: true,
 	})
 
@@ -31,3 +44,369 @@ func NewRedisStorage(details ConnectionDetails) fiber.Storage {
 
 	return redis
 }
+
+// Adds ARGV[2] to the set KEYS[1], unless it's empty, and extends the
+// expiration of the set to ARGV[1] milliseconds, it's never shortened.
+// The indexes written as JSON arrays by the previous versions are dropped.
+const sessionIndexAddScript = `
+if redis.call("TYPE", KEYS[1]).ok == "string" then
+	redis.call("DEL", KEYS[1])
+end
+
+if ARGV[2] ~= "" then
+	redis.call("SADD", KEYS[1], ARGV[2])
+end
+
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+
+return 0
+`
+
+// SessionIndex keeps track of the ids of the sessions of
+// each user, so they can be revoked, for example when the
+// user changes its password.
+//
+// The index of a user is stored under the key `user_sessions_<id>`,
+// as a set in Redis and as a JSON array in the other storages. It
+// expires along with the last session used, see `SessionIndex.Touch`.
+type SessionIndex struct {
+	// Serializes the updates of the indexes of the storages
+	// other than Redis, which are read, modified and written back.
+	mu sync.Mutex
+
+	storage fiber.Storage
+
+	// Set when the storage is Redis, whose
+	// indexes are updated by the server.
+	redis *redis.Storage
+
+	// Time the indexes live without being used, see `SetExpiration`.
+	expiration atomic.Int64
+}
+
+// Creates the index of the sessions kept in the provided storage.
+func NewSessionIndex(storage fiber.Storage) *SessionIndex {
+	si := &SessionIndex{
+		storage: storage,
+	}
+
+	si.redis, _ = storage.(*redis.Storage)
+	si.SetExpiration(SessionExpiration)
+
+	return si
+}
+
+// Sets the time the indexes live without being used, it
+// must be the time the sessions live without being used.
+func (si *SessionIndex) SetExpiration(expiration time.Duration) {
+	si.expiration.Store(int64(expiration))
+}
+
+func userSessionsKey(userId int) string {
+	return fmt.Sprintf("user_sessions_%d", userId)
+}
+
+// Reads the ids of the sessions of the user, the caller must hold the lock.
+func (si *SessionIndex) read(userId int) ([]string, error) {
+	raw, err := si.storage.Get(userSessionsKey(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+
+	if raw == nil {
+		return ids, nil
+	}
+
+	err = json.Unmarshal(raw, &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Writes the ids of the sessions of the user, the caller must hold the lock.
+func (si *SessionIndex) write(userId int, ids []string) error {
+	if len(ids) == 0 {
+		return si.storage.Delete(userSessionsKey(userId))
+	}
+
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	return si.storage.Set(userSessionsKey(userId), raw, time.Duration(si.expiration.Load()))
+}
+
+// Returns the ids of the sessions of the user.
+func (si *SessionIndex) members(userId int) ([]string, error) {
+	if si.redis != nil {
+		return si.redis.Conn().SMembers(context.Background(), userSessionsKey(userId)).Result()
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	return si.read(userId)
+}
+
+// Adds the session identified by sessionId (unless it's empty) to
+// the index of the user and extends the expiration of the index.
+func (si *SessionIndex) add(userId int, sessionId string) error {
+	expiration := time.Duration(si.expiration.Load())
+
+	if si.redis != nil {
+		return si.redis.Conn().Eval(
+			context.Background(),
+			sessionIndexAddScript,
+			[]string{userSessionsKey(userId)},
+			expiration.Milliseconds(),
+			sessionId,
+		).Err()
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	ids, err := si.read(userId)
+	if err != nil {
+		return err
+	}
+
+	known := false
+
+	for _, id := range ids {
+		if id == sessionId {
+			known = true
+		}
+	}
+
+	if sessionId != "" && !known {
+		ids = append(ids, sessionId)
+	}
+
+	// The index is written back even if unchanged to extend its expiration.
+	return si.write(userId, ids)
+}
+
+// Removes the sessions identified by sessionIds from the index of the user.
+func (si *SessionIndex) remove(userId int, sessionIds ...string) error {
+	if len(sessionIds) == 0 {
+		return nil
+	}
+
+	if si.redis != nil {
+		members := make([]any, len(sessionIds))
+
+		for i, id := range sessionIds {
+			members[i] = id
+		}
+
+		return si.redis.Conn().SRem(context.Background(), userSessionsKey(userId), members...).Err()
+	}
+
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	ids, err := si.read(userId)
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+
+	for _, id := range sessionIds {
+		removed[id] = true
+	}
+
+	remaining := []string{}
+
+	for _, id := range ids {
+		if !removed[id] {
+			remaining = append(remaining, id)
+		}
+	}
+
+	return si.write(userId, remaining)
+}
+
+// Reports whether the session identified by id is stored, the
+// sessions are deleted once revoked or expired.
+func (si *SessionIndex) Exists(id string) (bool, error) {
+	raw, err := si.storage.Get(id)
+	if err != nil {
+		return false, err
+	}
+
+	return raw != nil, nil
+}
+
+// Reads the data of the session identified by id, the
+// boolean is false if the session is no longer stored.
+func (si *SessionIndex) data(id string) (map[string]any, bool, error) {
+	raw, err := si.storage.Get(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if raw == nil {
+		return nil, false, nil
+	}
+
+	// Sessions are encoded by Fiber with gob.
+	data := map[string]any{}
+
+	err = gob.NewDecoder(bytes.NewReader(raw)).Decode(&data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// Adds the session identified by sessionId to the index of the user.
+func (si *SessionIndex) Add(userId int, sessionId string) error {
+	return si.add(userId, sessionId)
+}
+
+// Extends the expiration of the index of the user, it's called every
+// time one of the sessions of the user is used, so the index lives as
+// long as the last session used.
+func (si *SessionIndex) Touch(userId int) error {
+	return si.add(userId, "")
+}
+
+// Removes the session identified by sessionId from the index of the user.
+func (si *SessionIndex) Remove(userId int, sessionId string) error {
+	return si.remove(userId, sessionId)
+}
+
+// Returns the information of the active sessions of the user,
+// the expired sessions are dropped from the index.
+func (si *SessionIndex) List(userId int) ([]SessionInfo, error) {
+	ids, err := si.members(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []SessionInfo{}
+	expired := []string{}
+
+	for _, id := range ids {
+		data, ok, err := si.data(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			expired = append(expired, id)
+			continue
+		}
+
+		sessions = append(sessions, NewSessionInfo(id, data))
+	}
+
+	err = si.remove(userId, expired...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Deletes the session of the user whose public id (see `PublicSessionId`)
+// is publicId, it returns the id of the deleted session.
+func (si *SessionIndex) Revoke(userId int, publicId string) (string, error) {
+	ids, err := si.members(userId)
+	if err != nil {
+		return "", err
+	}
+
+	for _, id := range ids {
+		if PublicSessionId(id) != publicId {
+			continue
+		}
+
+		err = si.storage.Delete(id)
+		if err != nil {
+			return "", err
+		}
+
+		return id, si.remove(userId, id)
+	}
+
+	return "", ErrSessionNotFound
+}
+
+// Deletes every session of the user except the one identified
+// by exceptId, an empty exceptId deletes every session.
+func (si *SessionIndex) RevokeAll(userId int, exceptId string) error {
+	ids, err := si.members(userId)
+	if err != nil {
+		return err
+	}
+
+	revoked := []string{}
+
+	for _, id := range ids {
+		if id == exceptId {
+			continue
+		}
+
+		err := si.storage.Delete(id)
+		if err != nil {
+			return err
+		}
+
+		revoked = append(revoked, id)
+	}
+
+	return si.remove(userId, revoked...)
+}
+
+// SessionInfo represents the information of
+// a session shown to the owner of the session.
+type SessionInfo struct {
+	// Public id of the session, see `PublicSessionId`.
+	Id string `json:"id"`
+
+	// The User-Agent of the client that logged in.
+	Device string `json:"device"`
+
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+
+	// Whether it's the session of the request.
+	Current bool `json:"current"`
+}
+
+// Creates the information of the session identified
+// by id from the data stored in the session.
+func NewSessionInfo(id string, data map[string]any) SessionInfo {
+	device, _ := data[SessionDeviceKey].(string)
+	ip, _ := data[SessionIPKey].(string)
+	createdAt, _ := data[SessionCreatedAtKey].(int64)
+	lastSeenAt, _ := data[SessionLastSeenAtKey].(int64)
+
+	return SessionInfo{
+		Id:         PublicSessionId(id),
+		Device:     device,
+		IP:         ip,
+		CreatedAt:  time.Unix(createdAt, 0).UTC(),
+		LastSeenAt: time.Unix(lastSeenAt, 0).UTC(),
+	}
+}
+
+// Returns the id used to refer to the session identified by id
+// in the API, the session id itself is never exposed since
+// it's enough to impersonate the user.
+func PublicSessionId(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
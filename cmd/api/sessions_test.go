@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSessionIndex(t *testing.T) {
+	storage := NewSessionStore(nil).Storage
+
+	si := NewSessionIndex(storage)
+	si.SetExpiration(time.Second)
+
+	// Sessions are encoded by Fiber with gob.
+	var data bytes.Buffer
+
+	err := gob.NewEncoder(&data).Encode(map[string]any{SessionDeviceKey: "curl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = storage.Set("alive", data.Bytes(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"alive", "expired", "alive"} {
+		err := si.Add(7, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The expired session is dropped from the index.
+	sessions, err := si.List(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 1 || sessions[0].Device != "curl" {
+		t.Errorf("expected the alive session, got %+v", sessions)
+	}
+
+	ids, err := si.members(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"alive"}) {
+		t.Errorf("expected ids %v, got %v", []string{"alive"}, ids)
+	}
+
+	// The index expires once its sessions aren't used.
+	time.Sleep(2500 * time.Millisecond)
+
+	ids, err = si.members(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 0 {
+		t.Errorf("expected the index to expire, got %v", ids)
+	}
+}
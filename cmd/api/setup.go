@@ -8,17 +8,41 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-func (g *Global) Setup() *fiber.App {
+// Creates the app and registers its routes, config is
+// the configuration the server started with.
+func (g *Global) Setup(config Config) *fiber.App {
 	app := fiber.New(fiber.Config{
 		AppName:       "Nameless",
 		ServerHeader:  "Go+FiberV2",
 		CaseSensitive: true,
 		StrictRouting: true,
+		ErrorHandler:  g.ErrorHandler,
+		BodyLimit:     config.Server.BodyLimit,
+
+		// The address of the client (`fiber.Ctx.IP`) is read from the
+		// header only for the trusted proxies, when it's not valid the
+		// address of the connection is used.
+		ProxyHeader:             config.Server.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          config.Server.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 
-	// Define global middlewares.
+	// The panics are recovered first, so their requests are measured too.
 	app.Use(
+		g.Metrics.Middleware,
 		recover.New(),
+	)
+
+	// Define the probes of the orchestrator and the metrics, they're
+	// registered before the other middlewares so they don't create
+	// sessions nor fill the request log.
+	app.Get("/healthz", g.Healthz)
+	app.Get("/readyz", g.Readyz)
+	app.Get("/metrics", g.Metrics.Handler())
+
+	// Define global middlewares.
+	app.Use(
 		logger.New(),
 		g.ManageSession,
 	)
@@ -26,17 +50,54 @@ func (g *Global) Setup() *fiber.App {
 	// Define profile images route.
 	app.Get("/images/profile/:id<guid>", g.ProfileManager.ServeImage)
 
+	// Define the WebSocket endpoint for real-time events.
+	app.Get("/ws", g.RequireAuth, g.RealtimeConnect)
+
 	// Group API endpoints under the same group.
 	api := app.Group("/api")
 
-	user := api.Group("/user")
+	user := api.Group("/user", g.RateLimit(RateLimitGroupUser))
 	user.Post("/login", g.UserLogIn)
 	user.Post("/signup", g.UserSignUp)
 	user.Post("/logout", g.RequireAuth, g.UserLogout)
 	user.Get("/status", g.UserStatus)
+	user.Put("/language", g.UserLanguage)
 	user.Patch("/update", g.RequireAuth, g.UserUpdate)
+	user.Post("/password", g.RequireAuth, g.UserChangePassword)
+	user.Delete("", g.RequireAuth, g.UserDelete)
+	user.Get("/sessions", g.RequireAuth, g.UserSessionList)
+	user.Delete("/sessions", g.RequireAuth, g.UserSessionRevokeAll)
+	user.Delete("/sessions/:id", g.RequireAuth, g.UserSessionRevoke)
 	user.Get("/data", g.RequireAuth, g.UserGet)
 
+	auth := api.Group("/auth", g.RateLimit(RateLimitGroupAuth))
+	auth.Post("/token", g.AuthToken)
+	auth.Post("/refresh", g.AuthRefresh)
+	auth.Post("/revoke", g.AuthRevoke)
+
+	conversations := api.Group("/conversations", g.RequireAuth, g.RateLimit(RateLimitGroupConversations))
+	conversations.Post("", g.ConversationNew)
+	conversations.Get("", g.ConversationList)
+	conversations.Get("/:id<int>", g.ConversationGet)
+	conversations.Post("/:id<int>/participants", g.ConversationAddParticipant)
+	conversations.Delete("/:id<int>/participants/:userId<int>", g.ConversationRemoveParticipant)
+	conversations.Post("/:id<int>/end", g.ConversationEnd)
+	conversations.Post("/:id<int>/messages", g.MessageNew)
+	conversations.Get("/:id<int>/messages", g.MessageHistory)
+	conversations.Get("/:id<int>/messages/:messageId<int>", g.MessageGet)
+
+	match := api.Group("/match", g.RequireAuth, g.RateLimit(RateLimitGroupMatch))
+	match.Post("", g.MatchJoin)
+	match.Delete("", g.MatchCancel)
+
+	// The document is generated once every route is registered.
+	var openAPI []byte
+
+	api.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Type("json")
+		return c.Send(openAPI)
+	})
+
 	// TODO: remove later.
 	api.Get("/hello", func(c *fiber.Ctx) error {
 		sess := g.GetSession(c)
@@ -48,5 +109,19 @@ func (g *Global) Setup() *fiber.App {
 		return c.SendString(fmt.Sprintf("Hello (%d)\n", visits))
 	})
 
+	routes := app.GetRoutes(true)
+
+	g.Metrics.SetRoutes(routes)
+
+	document, err := NewOpenAPI(routes)
+	if err != nil {
+		g.Logger.Error("OpenAPI document", err)
+	}
+
+	openAPI, err = EncodeOpenAPI(document)
+	if err != nil {
+		g.Logger.Error("OpenAPI document - encode", err)
+	}
+
 	return app
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Shuts down the server listening with app: it stops accepting
+// connections, the waiting users of the match queue are sent away,
+// the WebSocket connections are closed and the requests in flight
+// are given until timeout to finish. Finally the scheduled ends of
+// the conversations are canceled, once the running ones finish.
+//
+// It returns the error of the context if the connections didn't
+// finish in time, the resources of `Global` are not closed.
+func (g *Global) Shutdown(app *fiber.App, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// The listener is closed right away, then it
+	// waits for the connections to become idle.
+	appDone := make(chan error, 1)
+
+	go func() {
+		appDone <- app.Shutdown()
+	}()
+
+	// Otherwise the users waiting for a partner
+	// would hold their requests until the timeout.
+	g.MatchQueue.Close()
+
+	// The WebSocket connections are hijacked
+	// from the server, which doesn't wait for them.
+	hubErr := g.Hub.Shutdown(ctx)
+	if hubErr != nil {
+		g.Logger.Warn("websocket connections not drained", "err", hubErr)
+	}
+
+	var appErr error
+
+	select {
+	case appErr = <-appDone:
+	case <-ctx.Done():
+		appErr = ctx.Err()
+		g.Logger.Warn("requests not drained", "err", appErr)
+	}
+
+	g.Expiry.Stop()
+
+	if appErr != nil {
+		return appErr
+	}
+
+	return hubErr
+}
@@ -1,8 +1,18 @@
 package main
 
 import (
+	"sync/atomic"
+
+	"github.com/Edwing123/udem-chat-app/pkg/health"
+	"github.com/Edwing123/udem-chat-app/pkg/i18n"
 	"github.com/Edwing123/udem-chat-app/pkg/images/profile"
+	"github.com/Edwing123/udem-chat-app/pkg/lockout"
+	"github.com/Edwing123/udem-chat-app/pkg/matchmaking"
 	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/ratelimit"
+	"github.com/Edwing123/udem-chat-app/pkg/realtime"
+	"github.com/Edwing123/udem-chat-app/pkg/tokens"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/password"
 	"github.com/gofiber/fiber/v2/middleware/session"
 	"golang.org/x/exp/slog"
 )
@@ -10,8 +20,31 @@ import (
 type Global struct {
 	Logger         *slog.Logger
 	Store          *session.Store
+	Sessions       *SessionIndex
+	Tokens         *tokens.Manager
+	Lockout        *lockout.Limiter
+	RateLimiter    *ratelimit.Limiter
 	ProfileManager *profile.Manager
 	Database       *models.Database
+	Hub            *realtime.Hub
+	MatchQueue     *matchmaking.Queue
+	Expiry         *ExpiryScheduler
+	Messages       *i18n.Catalog
+	Health         *health.Checker
+	Metrics        *Metrics
+
+	// Policy applied to the data of the deleted users.
+	DeletionPolicy models.DeletionPolicy
+
+	// Rules the passwords chosen by the users must follow.
+	PasswordPolicy password.Policy
+
+	// Options of the reloadable sections of the configuration,
+	// replaced as a whole by `Global.ApplySettings`.
+	Settings atomic.Pointer[Settings]
+
+	// Minimum level of the records of the logger.
+	LogLevel *slog.LevelVar
 }
 
 // Represents a bad response.
@@ -27,36 +60,240 @@ type SuccessMessage[T any] struct {
 	Data T    `json:"data"`
 }
 
+// NewConversationRequest represents the body
+// of the request for creating a conversation.
+type NewConversationRequest struct {
+	// Duration of the conversation in seconds.
+	Duration int `json:"duration"`
+
+	// Ids of the users joining the conversation
+	// besides the user creating it.
+	Participants []int `json:"participants"`
+}
+
+// ParticipantRequest represents the body of the
+// request for adding a participant to a conversation.
+type ParticipantRequest struct {
+	UserId int `json:"userId"`
+}
+
+// NewMessageRequest represents the body of
+// the request for sending a message.
+type NewMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// ChangePasswordRequest represents the body of
+// the request for changing the user password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// LanguageRequest represents the body of the request
+// for choosing the language of the messages.
+type LanguageRequest struct {
+	Language string `json:"language"`
+}
+
+// DeleteUserRequest represents the body of the
+// request for deleting the user account.
+type DeleteUserRequest struct {
+	Password string `json:"password"`
+}
+
+// RefreshTokenRequest represents the body of the
+// requests for refreshing or revoking a refresh token.
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 // ConnectionDetails represents the information
 // needed to connect to database server.
 type ConnectionDetails struct {
 	User     string `json:"user"`
-	Password string `json:"password"`
+	Password string `json:"password" redact:"true"`
 	Port     uint16 `json:"port"`
 	Host     string `json:"host"`
 }
 
+// Database drivers, they select the
+// implementation of `models.Database`.
+const (
+	DriverSQLServer = "sqlserver"
+	DriverSQLite    = "sqlite"
+	DriverMemory    = "memory"
+)
+
+// DatabaseConfig represents the database options.
+type DatabaseConfig struct {
+	// The implementation of `models.Database` to use, one of
+	// `DriverSQLServer` (the default), `DriverSQLite` or `DriverMemory`.
+	Driver string `json:"driver"`
+
+	// Connection details, they're only needed by SQL Server.
+	ConnectionDetails
+
+	// Path of the database file, it's only needed by SQLite.
+	Path string `json:"path"`
+}
+
 // Config represents the configuration file.
 type Config struct {
 	// Redis connection details.
 	Redis ConnectionDetails `json:"redis"`
 
-	// Database connection details.
-	Database ConnectionDetails `json:"database"`
+	// Database options.
+	Database DatabaseConfig `json:"database"`
 
 	// Server options.
 	Server struct {
 		// The address where the HTTP will listen on.
-		Addr string
+		Addr string `json:"addr"`
+
+		// Seconds given to the requests and the WebSocket
+		// connections to finish when the server shuts down.
+		ShutdownTimeout int `json:"shutdownTimeout"`
+
+		// Maximum size in bytes of the body of the requests, the uploaded
+		// profile pictures must fit along with their multipart overhead
+		// (see `Images.MaxSize` and `MultipartOverhead`).
+		BodyLimit int `json:"bodyLimit"`
+
+		// Header set by the reverse proxy with the IP address of the
+		// client, for example "X-Real-IP". When it's empty the address
+		// of the connection is used.
+		ProxyHeader string `json:"proxyHeader"`
+
+		// Addresses or CIDR ranges of the proxies trusted to set the
+		// header of ProxyHeader, the header is ignored otherwise.
+		TrustedProxies []string `json:"trustedProxies"`
 	} `json:"server"`
 
 	// Directory where data generated by the API
 	// will be stored.
 	AppData string `json:"appdata"`
+
+	// Matchmaking options.
+	Match struct {
+		// Duration in seconds of the conversations
+		// created for the matched users.
+		Duration int `json:"duration"`
+
+		// Seconds a user waits for a partner in each request, the
+		// clients poll until matched. It's kept short, the users
+		// whose client went away stay in the queue until then.
+		Timeout int `json:"timeout"`
+	} `json:"match"`
+
+	// User accounts options.
+	User struct {
+		// What happens to the data of the users deleting their
+		// accounts, either "tombstone" (the default) or "delete".
+		DeletionPolicy models.DeletionPolicy `json:"deletionPolicy"`
+	} `json:"user"`
+
+	// Bearer tokens options.
+	Auth struct {
+		// Key used to sign the access tokens, when it's empty a random
+		// key is used, so the tokens are invalidated on restart.
+		Secret string `json:"secret" redact:"true"`
+
+		// Seconds the access tokens are valid for.
+		AccessTokenTTL int `json:"accessTokenTtl"`
+
+		// Seconds the refresh tokens are valid for.
+		RefreshTokenTTL int `json:"refreshTokenTtl"`
+	} `json:"auth"`
+
+	// Login brute-force protection options, the failed
+	// attempts are counted by user name and by IP address.
+	Login struct {
+		// Failures allowed before the delays start.
+		FreeAttempts int `json:"freeAttempts"`
+
+		// Failures after which the login is locked out.
+		MaxAttempts int `json:"maxAttempts"`
+
+		// Seconds of the first delay, it doubles with every failure.
+		BaseDelay int `json:"baseDelay"`
+
+		// Seconds the login is locked out for.
+		Lockout int `json:"lockout"`
+	} `json:"login"`
+
+	// Rules the passwords chosen by the users must follow.
+	Password password.Policy `json:"password"`
+
+	// Password hashing options, the hashes produced with other
+	// options are replaced when their users log in.
+	Hashing struct {
+		// Algorithm of the new hashes, either "bcrypt" (the default) or "argon2id".
+		Algorithm string `json:"algorithm"`
+
+		// Cost of the bcrypt hashes.
+		BcryptCost int `json:"bcryptCost"`
+
+		// Parameters of the argon2id hashes.
+		Argon2id struct {
+			// Number of passes over the memory.
+			Time int `json:"time"`
+
+			// Memory used in KiB.
+			Memory int `json:"memory"`
+
+			// Number of threads used.
+			Threads int `json:"threads"`
+		} `json:"argon2id"`
+	} `json:"hashing"`
+
+	// Rate limits by route group, see `RateLimitGroups`.
+	RateLimit map[string]RateLimitRule `json:"rateLimit"`
+
+	// Logging options.
+	Log struct {
+		// Minimum level of the logged records, one of
+		// "debug", "info" (the default), "warn" or "error".
+		Level string `json:"level"`
+	} `json:"log"`
+
+	// Sessions options.
+	Session struct {
+		// Seconds a session lives without being used.
+		Expiration int `json:"expiration"`
+	} `json:"session"`
+
+	// Uploaded images options.
+	Images struct {
+		// Maximum size in bytes of the profile pictures.
+		MaxSize int64 `json:"maxSize"`
+	} `json:"images"`
+
+	// Readiness checks options, see `NewHealthChecker`.
+	Health struct {
+		// Seconds a check is given before it's considered failed.
+		Timeout int `json:"timeout"`
+
+		// Seconds the results of the checks are reused for.
+		CacheTTL int `json:"cacheTtl"`
+
+		// Minimum bytes available in the disk of the appdata directory.
+		MinFreeDisk uint64 `json:"minFreeDisk"`
+	} `json:"health"`
+}
+
+// RateLimitRule represents the rate limit of a route group.
+type RateLimitRule struct {
+	// Requests allowed per window, zero disables the limit.
+	Limit int `json:"limit"`
+
+	// Seconds of the window.
+	Window int `json:"window"`
 }
 
 // Flags represents the command line flags passed
 // to the executable.
 type Flags struct {
-	ConfigPath string // Path of the configuration file.
+	ConfigPath string          // Path of the configuration file.
+	Overrides  ConfigOverrides // Configuration values set with -set.
 }
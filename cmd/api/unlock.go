@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Edwing123/udem-chat-app/pkg/lockout"
+)
+
+const unlockUsage = `Usage: api unlock [-config=<path/to/config/file>] [-set=<key>=<value>...] [-user=<name>] [-ip=<address>]
+
+	-user  forgets the failed logins of the user name
+	-ip    forgets the failed logins of the IP address`
+
+// Runs the subcommand `unlock`, which lifts the login lockout of
+// a user name and/or an IP address. It returns the exit code.
+func RunUnlock(args []string) int {
+	flagSet := flag.NewFlagSet("unlock", flag.ExitOnError)
+	flags := DefineConfigFlags(flagSet)
+	user := flagSet.String("user", "", "The user name to unlock")
+	ip := flagSet.String("ip", "", "The IP address to unlock")
+	flagSet.Parse(args)
+
+	if *user == "" && *ip == "" {
+		fmt.Println(unlockUsage)
+		return 2
+	}
+
+	config := MustLoadConfig(*flags)
+
+	storage := NewRedisStorage(config.Redis)
+	defer storage.Close()
+
+	var keys []string
+
+	if *user != "" {
+		keys = append(keys, lockout.UserKey(*user))
+	}
+
+	if *ip != "" {
+		keys = append(keys, lockout.IPKey(*ip))
+	}
+
+	err := NewLoginLimiter(config, storage).Reset(keys...)
+	if err != nil {
+		fmt.Println("An error occured while unlocking:")
+		fmt.Println()
+
+		fmt.Println(err)
+
+		fmt.Println()
+		return 1
+	}
+
+	fmt.Println("unlocked")
+
+	return 0
+}
@@ -1,7 +1,26 @@
 package codes
 
+import "net/http"
+
+// HTTP status of the responses of the codes created with `NewCode`.
+const DefaultStatus = http.StatusBadRequest
+
+// HTTP status of the responses of each code, the codes are created
+// on initialization, so the map is only read afterwards.
+var statuses = map[Code]int{}
+
+// Creates a code whose responses have the status `DefaultStatus`.
 func NewCode(description string) Code {
-	return Code(description)
+	return NewCodeWithStatus(description, DefaultStatus)
+}
+
+// Creates a code whose responses have the provided HTTP status,
+// the codes must be package-level variables.
+func NewCodeWithStatus(description string, status int) Code {
+	code := Code(description)
+	statuses[code] = status
+
+	return code
 }
 
 // Represents an error code.
@@ -11,3 +30,65 @@ type Code string
 func (c Code) Error() string {
 	return string(c)
 }
+
+// Returns the HTTP status of the responses of the code.
+func (c Code) Status() int {
+	status, ok := statuses[c]
+	if !ok {
+		return DefaultStatus
+	}
+
+	return status
+}
+
+// Returns the key of the default message describing the code.
+func (c Code) MessageKey() string {
+	return "error." + string(c)
+}
+
+// DetailedError pairs a code with the details of its
+// response, which replace the default message of the code.
+type DetailedError struct {
+	Code    Code
+	Details any
+}
+
+// Creates an error with the code and the details of its response.
+func WithDetails(code Code, details any) error {
+	return &DetailedError{
+		Code:    code,
+		Details: details,
+	}
+}
+
+func (e *DetailedError) Error() string {
+	return e.Code.Error()
+}
+
+func (e *DetailedError) Unwrap() error {
+	return e.Code
+}
+
+// ParamsError pairs a code with the values of the
+// parameters of its default message by name.
+type ParamsError struct {
+	Code   Code
+	Params map[string]any
+}
+
+// Creates an error with the code and the values
+// of the parameters of its default message.
+func WithParams(code Code, params map[string]any) error {
+	return &ParamsError{
+		Code:   code,
+		Params: params,
+	}
+}
+
+func (e *ParamsError) Error() string {
+	return e.Code.Error()
+}
+
+func (e *ParamsError) Unwrap() error {
+	return e.Code
+}
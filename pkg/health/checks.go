@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Returns a check that fails if a file can't be created in dir.
+func Writable(dir string) Check {
+	return func(ctx context.Context) error {
+		file, err := os.CreateTemp(dir, ".health-*")
+		if err != nil {
+			return err
+		}
+
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString("ok")
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		return file.Close()
+	}
+}
+
+// Returns a check that fails if the file system of dir
+// has less than min bytes available.
+func FreeDisk(dir string, min uint64) Check {
+	return func(ctx context.Context) error {
+		available, err := availableBytes(dir)
+		if err != nil {
+			return err
+		}
+
+		if available < min {
+			return fmt.Errorf("%d bytes available, %d required", available, min)
+		}
+
+		return nil
+	}
+}
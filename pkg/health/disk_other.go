@@ -0,0 +1,12 @@
+//go:build !unix
+
+package health
+
+import (
+	"errors"
+)
+
+// The free disk space is only known on Unix systems.
+func availableBytes(dir string) (uint64, error) {
+	return 0, errors.New("free disk space not supported on this system")
+}
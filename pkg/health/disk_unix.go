@@ -0,0 +1,20 @@
+//go:build unix
+
+package health
+
+import (
+	"syscall"
+)
+
+// Returns the bytes available to unprivileged
+// users in the file system of dir.
+func availableBytes(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+
+	err := syscall.Statfs(dir, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
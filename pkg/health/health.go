@@ -0,0 +1,160 @@
+// Package health runs the checks of the dependencies of the server
+// (the databases, the directories, the disk) and reports whether
+// each one is usable, the results are cached for a while so the
+// probes don't hit the dependencies on every request.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency is usable, it
+// should give up once the context is done.
+type Check func(ctx context.Context) error
+
+// Status of a check or of the whole report.
+type Status string
+
+const (
+	StatusOk   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a check.
+type Result struct {
+	Status Status `json:"status"`
+
+	// Why the check failed, nil when it passed. It's not part of
+	// the JSON since it can reveal details of the infrastructure.
+	Err error `json:"-"`
+
+	// Milliseconds the check took.
+	Duration int64 `json:"durationMs"`
+}
+
+// Report is the outcome of every check, its status
+// is `StatusFail` if any of the checks failed.
+type Report struct {
+	Status    Status            `json:"status"`
+	CheckedAt time.Time         `json:"checkedAt"`
+	Checks    map[string]Result `json:"checks"`
+}
+
+// Options of the checker.
+type Options struct {
+	// Time a check is given before it's considered failed.
+	Timeout time.Duration
+
+	// Time the report is reused for, zero disables the cache.
+	CacheTTL time.Duration
+}
+
+// Checker runs the checks and caches their report.
+type Checker struct {
+	// Serializes the runs, so the requests arriving while
+	// the checks run wait for their report instead of
+	// running the checks again.
+	mu sync.Mutex
+
+	checks map[string]Check
+	report *Report
+
+	options Options
+}
+
+// Creates a checker without checks, see `Checker.Add`.
+func New(options Options) *Checker {
+	return &Checker{
+		checks:  map[string]Check{},
+		options: options,
+	}
+}
+
+// Adds the check identified by name, a check with the
+// same name is replaced. It must be called before the
+// checker is used.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Runs the checks concurrently and returns their report,
+// the report is reused until its cache expires.
+func (c *Checker) Run() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.options.CacheTTL {
+		return *c.report
+	}
+
+	report := Report{
+		Status:    StatusOk,
+		CheckedAt: time.Now(),
+		Checks:    map[string]Result{},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range c.checks {
+		wg.Add(1)
+
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			result := c.run(check)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[name] = result
+
+			if result.Status != StatusOk {
+				report.Status = StatusFail
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	c.report = &report
+
+	return report
+}
+
+// Runs the check until it returns or its timeout elapses,
+// the check is left running in the latter case.
+func (c *Checker) run(check Check) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.options.Timeout)
+	}
+
+	result := Result{
+		Status:   StatusOk,
+		Duration: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		result.Status = StatusFail
+		result.Err = err
+	}
+
+	return result
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	checker := New(Options{Timeout: 50 * time.Millisecond, CacheTTL: time.Hour})
+
+	runs := 0
+
+	checker.Add("ok", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	checker.Add("writable", Writable(t.TempDir()))
+
+	report := checker.Run()
+	if report.Status != StatusOk || len(report.Checks) != 2 {
+		t.Errorf("expected the checks passed, got %+v", report)
+	}
+
+	checker.Add("fail", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	})
+
+	// The report is cached.
+	report = checker.Run()
+	if report.Status != StatusOk || runs != 1 {
+		t.Errorf("expected the cached report, got %+v after %d runs", report, runs)
+	}
+
+	checker = New(Options{Timeout: 50 * time.Millisecond})
+
+	checker.Add("fail", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	})
+
+	// The checks ignoring the context are given up as well.
+	checker.Add("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	checker.Add("disk", FreeDisk(t.TempDir(), 1<<62))
+
+	report = checker.Run()
+	if report.Status != StatusFail {
+		t.Errorf("expected the report failed, got %+v", report)
+	}
+
+	for name, result := range report.Checks {
+		if result.Status != StatusFail || result.Err == nil {
+			t.Errorf("%s: expected the check failed, got %+v", name, result)
+		}
+	}
+
+	if result := report.Checks["slow"]; result.Duration >= 1000 {
+		t.Errorf("expected the slow check timed out, got %+v", result)
+	}
+}
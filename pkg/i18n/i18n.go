@@ -0,0 +1,169 @@
+// Package i18n provides the messages shown to the users in their
+// language, the messages of every language are kept in a bundle
+// (a JSON file of `locales`) embedded in the binary.
+//
+// The messages are templates, their parameters are written as
+// `{name}` and replaced by the values of `Params`.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+// Languages of the embedded bundles.
+const (
+	LanguageSpanish = "es"
+	LanguageEnglish = "en"
+)
+
+// Language of the messages when the user has no preference.
+const DefaultLanguage = LanguageSpanish
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Matches the parameters of the messages.
+var paramPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// Params are the values of the parameters of a message by name.
+type Params = map[string]any
+
+// Catalog holds the messages of every language by key.
+type Catalog struct {
+	// Language used for the languages and messages not available.
+	fallback string
+
+	bundles map[string]map[string]string
+}
+
+// Creates a catalog with the embedded bundles, the messages missing
+// in a language are taken from the fallback language.
+func New(fallback string) (*Catalog, error) {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		fallback: fallback,
+		bundles:  map[string]map[string]string{},
+	}
+
+	for _, entry := range entries {
+		data, err := locales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var bundle map[string]string
+
+		err = json.Unmarshal(data, &bundle)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", entry.Name(), err)
+		}
+
+		language := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		catalog.bundles[language] = bundle
+	}
+
+	if !catalog.Has(fallback) {
+		return nil, fmt.Errorf("no bundle for the fallback language %q", fallback)
+	}
+
+	return catalog, nil
+}
+
+// Reports whether there's a bundle for the language.
+func (c *Catalog) Has(language string) bool {
+	_, ok := c.bundles[language]
+	return ok
+}
+
+// Returns the languages of the bundles in alphabetical order.
+func (c *Catalog) Languages() []string {
+	languages := make([]string, 0, len(c.bundles))
+
+	for language := range c.bundles {
+		languages = append(languages, language)
+	}
+
+	sort.Strings(languages)
+
+	return languages
+}
+
+// Returns the language of the bundles that best matches the
+// value of the header `Accept-Language`, the region of the
+// languages is ignored (`en-US` matches `en`). The fallback
+// language is returned if none matches.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	best := c.fallback
+	bestQuality := 0.0
+
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag, weight, _ := strings.Cut(strings.TrimSpace(item), ";")
+
+		quality := 1.0
+
+		if weight = strings.TrimSpace(weight); strings.HasPrefix(weight, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(weight, "q="), 64)
+			if err != nil {
+				continue
+			}
+
+			quality = parsed
+		}
+
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+
+		// The first language wins between languages of the same quality.
+		if c.Has(language) && quality > bestQuality {
+			best = language
+			bestQuality = quality
+		}
+	}
+
+	return best
+}
+
+// Returns the message identified by key in the language with its
+// parameters replaced, the key itself is returned if no bundle has
+// the message. The parameters without a value are left as they are.
+func (c *Catalog) Message(language string, key string, params Params) string {
+	message, ok := c.bundles[language][key]
+	if !ok {
+		message, ok = c.bundles[c.fallback][key]
+	}
+
+	if !ok {
+		return key
+	}
+
+	if len(params) == 0 {
+		return message
+	}
+
+	return paramPattern.ReplaceAllStringFunc(message, func(param string) string {
+		value, ok := params[param[1:len(param)-1]]
+		if !ok {
+			return param
+		}
+
+		return fmt.Sprint(value)
+	})
+}
+
+// Returns the message describing the code in the language
+// (see `codes.Code.MessageKey`) with its parameters replaced.
+func (c *Catalog) Error(language string, code codes.Code, params Params) string {
+	return c.Message(language, code.MessageKey(), params)
+}
@@ -0,0 +1,53 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog, err := New(DefaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every bundle must have the messages of the fallback language.
+	for _, language := range catalog.Languages() {
+		for key := range catalog.bundles[DefaultLanguage] {
+			if _, ok := catalog.bundles[language][key]; !ok {
+				t.Errorf("%s: missing message %q", language, key)
+			}
+		}
+	}
+
+	negotiations := map[string]string{
+		"":                          DefaultLanguage,
+		"en":                        LanguageEnglish,
+		"en-US,en;q=0.9":            LanguageEnglish,
+		"fr-FR, es;q=0.5, en;q=0.8": LanguageEnglish,
+		"en;q=0.2, ES":              LanguageSpanish,
+		"fr, de":                    DefaultLanguage,
+		"en;q=bad, es;q=0.1":        LanguageSpanish,
+	}
+
+	for acceptLanguage, expected := range negotiations {
+		language := catalog.Negotiate(acceptLanguage)
+		if language != expected {
+			t.Errorf("Negotiate(%q): expected %q, got %q", acceptLanguage, expected, language)
+		}
+	}
+
+	message := catalog.Message(LanguageEnglish, "user.signed_up", Params{"name": "ana"})
+	if message != "User ana signed up" {
+		t.Errorf("expected the parameters replaced, got %q", message)
+	}
+
+	message = catalog.Message("fr", "user.signed_up", nil)
+	if message != "Usuario {name} registrado" {
+		t.Errorf("expected the message of the fallback language, got %q", message)
+	}
+
+	message = catalog.Message(LanguageEnglish, "unknown.key", nil)
+	if message != "unknown.key" {
+		t.Errorf("expected the key of the unknown message, got %q", message)
+	}
+}
@@ -1,14 +1,16 @@
 package profile
 
 import (
+	"net/http"
+
 	"github.com/Edwing123/udem-chat-app/pkg/codes"
 )
 
 var (
 	ErrImageTypeNotSupported = codes.NewCode("image_type_not_supported")
-	ErrImageProcessFail      = codes.NewCode("image_process_fail")
+	ErrImageProcessFail      = codes.NewCodeWithStatus("image_process_fail", http.StatusInternalServerError)
 	ErrCannotGetImageSize    = codes.NewCode("cannot_get_image_size")
-	ErrImageConvertionFail   = codes.NewCode(("image_convertion_fail"))
-	ErrImageWriteFail        = codes.NewCode(("image_write_fail"))
-	ErrImageArchiveFail      = codes.NewCode(("image_archive_fail"))
+	ErrImageConvertionFail   = codes.NewCodeWithStatus("image_convertion_fail", http.StatusInternalServerError)
+	ErrImageWriteFail        = codes.NewCodeWithStatus("image_write_fail", http.StatusInternalServerError)
+	ErrImageArchiveFail      = codes.NewCodeWithStatus("image_archive_fail", http.StatusInternalServerError)
 )
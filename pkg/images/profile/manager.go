@@ -3,6 +3,7 @@ package profile
 import (
 	"os"
 	"path"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -18,9 +19,39 @@ type Manager struct {
 	// the images.
 	rootDir string
 
+	// Called after every stage of the processing
+	// of the images, see `Manager.Observe`.
+	observer StageObserver
+
 	logger *slog.Logger
 }
 
+// Stages of the processing of the images.
+const (
+	StageProcess = "process"
+	StageCrop    = "crop"
+	StageResize  = "resize"
+	StageConvert = "convert"
+	StageSave    = "save"
+)
+
+// StageObserver receives the duration and the
+// error (nil on success) of a processing stage.
+type StageObserver func(stage string, duration time.Duration, err error)
+
+// Sets the function called after every stage of the processing
+// of the images, it must be called before the manager is used.
+func (pm *Manager) Observe(observer StageObserver) {
+	pm.observer = observer
+}
+
+// Reports the stage started at start to the observer, if any.
+func (pm *Manager) observe(stage string, start time.Time, err error) {
+	if pm.observer != nil {
+		pm.observer(stage, time.Since(start), err)
+	}
+}
+
 // InitDirs creates the directories where
 // the images will be stored.
 //
@@ -74,6 +105,11 @@ func (pm *Manager) InitDirs() error {
 	return nil
 }
 
+// Returns the directory of the images that can be served.
+func (pm *Manager) ActiveDir() string {
+	return path.Join(pm.rootDir, activeDir)
+}
+
 const (
 	activeDir   = "active"
 	archiveDir  = "archive"
@@ -96,7 +132,10 @@ func (pm *Manager) New(image Image, crop Crop) (string, error) {
 		return "", ErrImageTypeNotSupported
 	}
 
+	start := time.Now()
+
 	originalImageBuffer, err := pm.process(image.Buffer, defaultProcessOptions)
+	pm.observe(StageProcess, start, err)
 	if err != nil {
 		pm.logger.Error("Process original image", err, "imageType", image.Type)
 		return "", ErrImageProcessFail
@@ -105,17 +144,22 @@ func (pm *Manager) New(image Image, crop Crop) (string, error) {
 	// Create a unique id for the image.
 	imageId := uuid.New().String()
 
+	start = time.Now()
+
 	err = pm.save(imageId, "original", originalImageBuffer)
+	pm.observe(StageSave, start, err)
 	if err != nil {
 		pm.logger.Error("Save original image", err, "imageType", image.Type, "imageId", imageId)
 		return "", ErrImageWriteFail
 	}
 
 	// Crop the image.
+	start = time.Now()
 	bImage := bimg.NewImage(originalImageBuffer)
 
 	imageSize, err := bImage.Size()
 	if err != nil {
+		pm.observe(StageCrop, start, err)
 		pm.logger.Error("Get image size", err, "imageType", image.Type, "imageId", imageId)
 		return "", ErrCannotGetImageSize
 	}
@@ -134,13 +178,17 @@ func (pm *Manager) New(image Image, crop Crop) (string, error) {
 		Top:    cropInPixels.Y,
 		Crop:   true,
 	})
+	pm.observe(StageCrop, start, err)
 	if err != nil {
 		pm.logger.Error("Process image crop", err, "imageType", image.Type, "imageId", imageId)
 		return "", ErrImageProcessFail
 	}
 
 	// Resize the image.
+	start = time.Now()
+
 	croppedAndResizedImageBuffer, err := bimg.NewImage(croppedImageBuffer).Resize(400, 400)
+	pm.observe(StageResize, start, err)
 	if err != nil {
 		pm.logger.Error("Process image resize", err, "imageType", image.Type, "imageId", imageId)
 		return "", ErrImageProcessFail
@@ -154,7 +202,10 @@ func (pm *Manager) New(image Image, crop Crop) (string, error) {
 		},
 	}
 
+	start = time.Now()
+
 	convertedImages, err := pm.convert(images[0])
+	pm.observe(StageConvert, start, err)
 	if err != nil {
 		return "", err
 	}
@@ -165,7 +216,10 @@ func (pm *Manager) New(image Image, crop Crop) (string, error) {
 	for _, image := range images {
 		typeName := bimg.ImageTypeName(image.Type)
 
+		start := time.Now()
+
 		err := pm.save(imageId, path.Join(activeDir, typeName), image.Buffer)
+		pm.observe(StageSave, start, err)
 		if err != nil {
 			pm.logger.Error("Save image", err, "imageType", image.Type, "imageId", imageId)
 			return "", err
@@ -0,0 +1,28 @@
+package lockout
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrLocked = codes.NewCodeWithStatus("login_locked", http.StatusTooManyRequests)
+)
+
+// LockedError is returned for the locked keys, it
+// tells the time left before the key is unlocked.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrLocked, e.RetryAfter)
+}
+
+// Makes `errors.Is(err, ErrLocked)` work.
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
@@ -0,0 +1,444 @@
+// Package lockout protects the login against brute-force attacks, it
+// counts the failed attempts by key (a user name or an IP address)
+// and blocks the keys with too many failures, first with delays that
+// grow exponentially and then with a temporary lockout.
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+)
+
+// Default time the attempts reserved by `Limiter.Check` are remembered
+// for, see `Options.PendingTTL`.
+const DefaultPendingTTL = time.Minute
+
+// Defines the function delay of the scripts, the counterpart of
+// `Limiter.delay`, with the options passed by `Limiter.eval`.
+const delayFunction = `
+local now = tonumber(ARGV[1])
+local free = tonumber(ARGV[2])
+local max = tonumber(ARGV[3])
+local base = tonumber(ARGV[4])
+local lockout = tonumber(ARGV[5])
+
+local function delay(failures)
+	if failures >= max then
+		return lockout
+	end
+	if failures <= free then
+		return 0
+	end
+	return math.min(base * 2 ^ (failures - free - 1), lockout)
+end
+`
+
+// The counterpart of `Limiter.Check`, KEYS holds the counter of each key
+// (a hash) followed by its reserved attempts. It returns the milliseconds
+// left to wait, or zero once the attempts are reserved.
+const checkScript = delayFunction + `
+local retryAfter = 0
+
+for i = 1, #KEYS, 2 do
+	local failures = tonumber(redis.call("HGET", KEYS[i], "failures") or 0)
+	local blockedUntil = tonumber(redis.call("HGET", KEYS[i], "blockedUntil") or 0)
+	local pending = tonumber(redis.call("GET", KEYS[i + 1]) or 0)
+
+	local left = blockedUntil - now
+	if pending > 0 then
+		left = math.max(left, delay(failures + pending))
+	end
+
+	retryAfter = math.max(retryAfter, left)
+end
+
+if retryAfter > 0 then
+	return math.ceil(retryAfter)
+end
+
+for i = 1, #KEYS, 2 do
+	redis.call("INCR", KEYS[i + 1])
+	redis.call("PEXPIRE", KEYS[i + 1], ARGV[6])
+end
+
+return 0
+`
+
+// The counterpart of `Limiter.Fail`, KEYS is laid out as in checkScript.
+const failScript = delayFunction + `
+for i = 1, #KEYS, 2 do
+	local failures = redis.call("HINCRBY", KEYS[i], "failures", 1)
+
+	local blockedFor = delay(failures)
+	if blockedFor > 0 then
+		redis.call("HSET", KEYS[i], "blockedUntil", math.floor(now + blockedFor))
+	end
+
+	redis.call("PEXPIRE", KEYS[i], ARGV[5])
+
+	if tonumber(redis.call("GET", KEYS[i + 1]) or 0) > 0 then
+		redis.call("DECR", KEYS[i + 1])
+	end
+end
+
+return 0
+`
+
+// The counterpart of `Limiter.Settle`, KEYS holds the reserved attempts of each key.
+const settleScript = `
+for i = 1, #KEYS do
+	if tonumber(redis.call("GET", KEYS[i]) or 0) > 0 then
+		redis.call("DECR", KEYS[i])
+	end
+end
+
+return 0
+`
+
+// Limiter counts the failures of the keys, the
+// counters are kept in the provided storage.
+type Limiter struct {
+	// Serializes the updates of the counters of the storages
+	// other than Redis, which are read, modified and written back.
+	mu sync.Mutex
+
+	storage fiber.Storage
+
+	// Set when the storage is Redis, whose counters are
+	// updated atomically by the server with scripts.
+	redis *redis.Storage
+
+	options Options
+}
+
+// Options of the limiter.
+type Options struct {
+	// Failures allowed before the delays start.
+	FreeAttempts int
+
+	// Failures after which the key is locked out.
+	MaxAttempts int
+
+	// The delay after the first failure beyond the free
+	// attempts, it doubles with every failure after it.
+	BaseDelay time.Duration
+
+	// Time a key is locked out for, it's also the maximum delay
+	// and the time the failures are remembered for.
+	Lockout time.Duration
+
+	// Time the attempts reserved by `Limiter.Check` are remembered for,
+	// `DefaultPendingTTL` if zero. It must be longer than any login takes,
+	// the attempts never finished (for example because the server stopped
+	// meanwhile) stop blocking their keys after it.
+	PendingTTL time.Duration
+}
+
+// The counter stored for each key by the storages other than Redis.
+type state struct {
+	Failures     int       `json:"failures"`
+	BlockedUntil time.Time `json:"blockedUntil"`
+}
+
+// Creates a limiter which keeps the counters in the provided storage.
+func New(storage fiber.Storage, options Options) *Limiter {
+	l := &Limiter{
+		storage: storage,
+		options: options,
+	}
+
+	if l.options.PendingTTL == 0 {
+		l.options.PendingTTL = DefaultPendingTTL
+	}
+
+	l.redis, _ = storage.(*redis.Storage)
+
+	return l
+}
+
+// Returns the key of the counter of the user name,
+// the names are compared without case.
+func UserKey(name string) string {
+	return "login_failures_user_" + strings.ToLower(name)
+}
+
+// Returns the key of the counter of the IP address.
+func IPKey(ip string) string {
+	return "login_failures_ip_" + ip
+}
+
+// Returns the key of the number of attempts of the key
+// reserved by `Limiter.Check` and not finished yet.
+func pendingKey(key string) string {
+	return key + "_pending"
+}
+
+// Runs the script on Redis with the options of the limiter,
+// keys are passed along with their reserved attempts.
+func (l *Limiter) eval(script string, keys []string) (int, error) {
+	scriptKeys := make([]string, 0, len(keys)*2)
+
+	for _, key := range keys {
+		scriptKeys = append(scriptKeys, key, pendingKey(key))
+	}
+
+	return l.redis.Conn().Eval(
+		context.Background(),
+		script,
+		scriptKeys,
+		time.Now().UnixMilli(),
+		l.options.FreeAttempts,
+		l.options.MaxAttempts,
+		l.options.BaseDelay.Milliseconds(),
+		l.options.Lockout.Milliseconds(),
+		l.options.PendingTTL.Milliseconds(),
+	).Int()
+}
+
+// Reads the counter of the key, the caller must hold the lock.
+func (l *Limiter) read(key string) (state, error) {
+	raw, err := l.storage.Get(key)
+	if err != nil || raw == nil {
+		return state{}, err
+	}
+
+	var s state
+
+	err = json.Unmarshal(raw, &s)
+	if err != nil {
+		return state{}, err
+	}
+
+	return s, nil
+}
+
+// Writes the counter of the key, the caller must hold the lock.
+func (l *Limiter) write(key string, s state) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return l.storage.Set(key, raw, l.options.Lockout)
+}
+
+// Reads the attempts of the key reserved by
+// `Limiter.Check`, the caller must hold the lock.
+func (l *Limiter) readPending(key string) (int, error) {
+	raw, err := l.storage.Get(pendingKey(key))
+	if err != nil || raw == nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(raw))
+}
+
+// Writes the attempts of the key reserved by
+// `Limiter.Check`, the caller must hold the lock.
+func (l *Limiter) writePending(key string, pending int) error {
+	if pending <= 0 {
+		return l.storage.Delete(pendingKey(key))
+	}
+
+	return l.storage.Set(pendingKey(key), []byte(strconv.Itoa(pending)), l.options.PendingTTL)
+}
+
+// Returns the time the key is blocked for after
+// the provided number of failures.
+func (l *Limiter) delay(failures int) time.Duration {
+	if failures >= l.options.MaxAttempts {
+		return l.options.Lockout
+	}
+
+	if failures <= l.options.FreeAttempts {
+		return 0
+	}
+
+	delay := l.options.BaseDelay
+
+	for i := l.options.FreeAttempts + 1; i < failures && delay < l.options.Lockout; i++ {
+		delay *= 2
+	}
+
+	if delay > l.options.Lockout {
+		return l.options.Lockout
+	}
+
+	return delay
+}
+
+// Checks none of the keys is blocked and reserves an attempt for each
+// of them, otherwise it fails with a `*LockedError` telling the longest
+// time left to wait. The reserved attempt must be finished with either
+// `Limiter.Fail` or `Limiter.Settle`.
+//
+// The attempts running are counted as failed, so the concurrent attempts
+// are blocked as if the ones running had failed, otherwise they'd all
+// pass the check before the first failure is recorded.
+func (l *Limiter) Check(keys ...string) error {
+	if l.redis != nil {
+		ms, err := l.eval(checkScript, keys)
+		if err != nil {
+			return err
+		}
+
+		if ms > 0 {
+			return &LockedError{RetryAfter: time.Duration(ms) * time.Millisecond}
+		}
+
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var retryAfter time.Duration
+
+	pendings := make([]int, len(keys))
+
+	for i, key := range keys {
+		s, err := l.read(key)
+		if err != nil {
+			return err
+		}
+
+		pending, err := l.readPending(key)
+		if err != nil {
+			return err
+		}
+
+		left := time.Until(s.BlockedUntil)
+
+		if pending > 0 {
+			if delay := l.delay(s.Failures + pending); delay > left {
+				left = delay
+			}
+		}
+
+		if left > retryAfter {
+			retryAfter = left
+		}
+
+		pendings[i] = pending
+	}
+
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+
+	for i, key := range keys {
+		err := l.writePending(key, pendings[i]+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Finishes an attempt reserved by `Limiter.Check`
+// for the key, the caller must hold the lock.
+func (l *Limiter) finish(key string) error {
+	pending, err := l.readPending(key)
+	if err != nil {
+		return err
+	}
+
+	// The reserved attempts could've been reset or expired meanwhile.
+	if pending == 0 {
+		return nil
+	}
+
+	return l.writePending(key, pending-1)
+}
+
+// Records a failed attempt for each of the keys, finishing
+// the attempts reserved by `Limiter.Check`.
+func (l *Limiter) Fail(keys ...string) error {
+	if l.redis != nil {
+		_, err := l.eval(failScript, keys)
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		s, err := l.read(key)
+		if err != nil {
+			return err
+		}
+
+		s.Failures++
+
+		if delay := l.delay(s.Failures); delay > 0 {
+			s.BlockedUntil = time.Now().Add(delay)
+		}
+
+		err = l.write(key, s)
+		if err != nil {
+			return err
+		}
+
+		err = l.finish(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Finishes the attempts reserved by `Limiter.Check` without
+// a failure, for example when the credentials were valid.
+func (l *Limiter) Settle(keys ...string) error {
+	if l.redis != nil {
+		pendingKeys := make([]string, len(keys))
+
+		for i, key := range keys {
+			pendingKeys[i] = pendingKey(key)
+		}
+
+		return l.redis.Conn().Eval(context.Background(), settleScript, pendingKeys).Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		err := l.finish(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Forgets the failures of the keys, it's used after a
+// successful login and to unlock the keys manually.
+func (l *Limiter) Reset(keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		err := l.storage.Delete(key)
+		if err != nil {
+			return err
+		}
+
+		err = l.storage.Delete(pendingKey(key))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package lockout
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// Returns the in-memory storage used by the sessions by default.
+func newMemoryStorage() fiber.Storage {
+	return session.New(session.Config{}).Storage
+}
+
+func TestDelay(t *testing.T) {
+	l := New(nil, Options{
+		FreeAttempts: 2,
+		MaxAttempts:  8,
+		BaseDelay:    time.Second,
+		Lockout:      10 * time.Second,
+	})
+
+	expected := []time.Duration{0, 0, 0, 1, 2, 4, 8, 10, 10, 10}
+
+	for failures, delay := range expected {
+		if got := l.delay(failures); got != delay*time.Second {
+			t.Errorf("%d failures: expected %s, got %s", failures, delay*time.Second, got)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := New(newMemoryStorage(), Options{
+		FreeAttempts: 1,
+		MaxAttempts:  3,
+		BaseDelay:    time.Minute,
+		Lockout:      time.Hour,
+	})
+
+	user, ip := UserKey("Edwin"), IPKey("127.0.0.1")
+
+	err := l.Fail(user, ip)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = l.Check(user, ip)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = l.Fail(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = l.Settle(ip)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// Only the user is blocked.
+	var locked *LockedError
+
+	err = l.Check(UserKey("EDWIN"), ip)
+	if !errors.As(err, &locked) || !errors.Is(err, ErrLocked) || locked.RetryAfter > time.Minute {
+		t.Errorf("expected %v with a retry after of a minute, got %v", ErrLocked, err)
+	}
+
+	err = l.Check(ip)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+
+	err = l.Reset(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = l.Check(user)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestLimiterConcurrentAttempts(t *testing.T) {
+	l := New(newMemoryStorage(), Options{
+		FreeAttempts: 2,
+		MaxAttempts:  5,
+		BaseDelay:    time.Minute,
+		Lockout:      time.Hour,
+	})
+
+	user := UserKey("edwin")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var passed int
+
+	// The attempts fail slowly, as the verification of the passwords.
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if l.Check(user) != nil {
+				return
+			}
+
+			mu.Lock()
+			passed++
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+			l.Fail(user)
+		}()
+	}
+
+	wg.Wait()
+
+	// The free attempts and the one after them, as if they ran one by one.
+	if passed != 3 {
+		t.Errorf("expected 3 attempts to pass, got %d", passed)
+	}
+
+	err := l.Check(user)
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("expected %v, got %v", ErrLocked, err)
+	}
+}
+
+func TestLimiterPendingExpiry(t *testing.T) {
+	l := New(newMemoryStorage(), Options{
+		FreeAttempts: 0,
+		MaxAttempts:  5,
+		BaseDelay:    time.Minute,
+		Lockout:      time.Hour,
+		PendingTTL:   time.Second,
+	})
+
+	user := UserKey("edwin")
+
+	// The attempt is never finished.
+	err := l.Check(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = l.Check(user)
+	if !errors.Is(err, ErrLocked) {
+		t.Errorf("expected %v, got %v", ErrLocked, err)
+	}
+
+	// The memory storage tells the time with a precision of a second.
+	time.Sleep(2500 * time.Millisecond)
+
+	err = l.Check(user)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package matchmaking
+
+import (
+	"net/http"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrAlreadyQueued = codes.NewCodeWithStatus("match_already_queued", http.StatusConflict)
+	ErrNotQueued     = codes.NewCodeWithStatus("match_not_queued", http.StatusNotFound)
+	ErrMatchTimeout  = codes.NewCodeWithStatus("match_timeout", http.StatusNotFound)
+	ErrMatchCanceled = codes.NewCodeWithStatus("match_canceled", http.StatusConflict)
+	ErrQueueClosed   = codes.NewCodeWithStatus("match_queue_closed", http.StatusServiceUnavailable)
+)
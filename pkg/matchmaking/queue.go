@@ -0,0 +1,301 @@
+package matchmaking
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"golang.org/x/exp/slog"
+)
+
+// Queue pairs the users waiting to chat with a stranger,
+// every pair of users gets a new conversation lasting
+// a fixed duration.
+type Queue struct {
+	mu sync.Mutex
+
+	// Users waiting for a partner, from the oldest to the newest.
+	waiting []*ticket
+
+	// Tickets of the waiting users by user id, the matched
+	// users keep their ticket until their conversation is
+	// created, so they can't join again in the meantime.
+	tickets map[int]*ticket
+
+	// The last partner of each matched user, users are not
+	// matched with their last partner again until the entry
+	// expires, see `lastPartnerTTL`.
+	lastPartner map[int]partner
+
+	// Time the entries of lastPartner are kept.
+	lastPartnerTTL time.Duration
+
+	// Whether the queue was closed, see `Queue.Close`.
+	closed bool
+
+	// Counts the matches creating their conversation.
+	matches sync.WaitGroup
+
+	conversations models.ConversationManager
+	options       Options
+
+	logger *slog.Logger
+}
+
+// Options of the queue.
+type Options struct {
+	// Duration in seconds of the created conversations.
+	Duration int
+
+	// Time a user waits for a partner before giving up.
+	Timeout time.Duration
+}
+
+// Result is the outcome of joining the queue, either the
+// conversation with the partner or the reason there's none.
+type Result struct {
+	Conversation models.Conversation
+	Err          error
+}
+
+// Time two matched users are not matched again.
+const lastPartnerTTL = time.Hour
+
+// Represents a user waiting in the queue.
+type ticket struct {
+	userId int
+	result chan Result
+	timer  *time.Timer
+
+	// Whether the user was matched, its
+	// conversation is being created.
+	matched bool
+
+	// Closed once the result is sent.
+	done chan struct{}
+}
+
+// Sends the result of the ticket, it must be called once.
+func (t *ticket) resolve(result Result) {
+	t.result <- result
+	close(t.done)
+}
+
+// Represents the last partner of a user.
+type partner struct {
+	userId    int
+	expiresAt time.Time
+}
+
+// Creates a queue which will create the conversations using
+// the provided conversation manager and will log messages
+// using the provided logger.
+func New(conversations models.ConversationManager, options Options, logger *slog.Logger) *Queue {
+	return &Queue{
+		tickets:        map[int]*ticket{},
+		lastPartner:    map[int]partner{},
+		lastPartnerTTL: lastPartnerTTL,
+		conversations:  conversations,
+		options:        options,
+		logger:         logger,
+	}
+}
+
+// Places the user identified by userId in the queue, the returned
+// channel receives a single result once the user is matched, the
+// wait times out or the user cancels it. The user leaves the queue
+// once ctx is done, for example when the client goes away.
+func (q *Queue) Join(ctx context.Context, userId int) (<-chan Result, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	if _, ok := q.tickets[userId]; ok {
+		return nil, ErrAlreadyQueued
+	}
+
+	t := &ticket{
+		userId: userId,
+		result: make(chan Result, 1),
+		done:   make(chan struct{}),
+	}
+
+	q.tickets[userId] = t
+
+	partner := q.findPartner(userId)
+	if partner == nil {
+		q.waiting = append(q.waiting, t)
+		t.timer = time.AfterFunc(q.options.Timeout, func() {
+			q.expire(t, ErrMatchTimeout)
+		})
+
+		if ctx.Done() != nil {
+			go func() {
+				select {
+				case <-ctx.Done():
+					q.expire(t, ErrMatchCanceled)
+				case <-t.done:
+				}
+			}()
+		}
+
+		return t.result, nil
+	}
+
+	q.dequeue(partner)
+	partner.matched = true
+	t.matched = true
+
+	q.matches.Add(1)
+	go q.match(partner, t)
+
+	return t.result, nil
+}
+
+// Removes the user identified by userId from the queue.
+func (q *Queue) Cancel(userId int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	t, ok := q.tickets[userId]
+	if !ok || t.matched {
+		return ErrNotQueued
+	}
+
+	q.remove(t)
+	t.resolve(Result{Err: ErrMatchCanceled})
+
+	return nil
+}
+
+// Closes the queue, the waiting users get `ErrQueueClosed` and
+// so do the users joining afterwards. It waits for the matches
+// that are creating their conversation.
+func (q *Queue) Close() {
+	q.mu.Lock()
+
+	q.closed = true
+
+	for _, t := range q.waiting {
+		t.timer.Stop()
+		delete(q.tickets, t.userId)
+		t.resolve(Result{Err: ErrQueueClosed})
+	}
+
+	q.waiting = nil
+
+	q.mu.Unlock()
+
+	q.matches.Wait()
+}
+
+// Returns the oldest waiting user that can be matched with the
+// user identified by userId, or nil if there is none.
+func (q *Queue) findPartner(userId int) *ticket {
+	now := time.Now()
+
+	for _, t := range q.waiting {
+		if q.isLastPartner(userId, t.userId, now) || q.isLastPartner(t.userId, userId, now) {
+			continue
+		}
+
+		return t
+	}
+
+	return nil
+}
+
+// Reports whether the user identified by partnerId is the
+// last partner of the user identified by userId, the
+// caller must hold the lock of the queue.
+func (q *Queue) isLastPartner(userId, partnerId int, now time.Time) bool {
+	last, ok := q.lastPartner[userId]
+	return ok && last.userId == partnerId && now.Before(last.expiresAt)
+}
+
+// Removes the ticket from the waiting users, the
+// caller must hold the lock of the queue.
+func (q *Queue) dequeue(t *ticket) {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	for i, waiting := range q.waiting {
+		if waiting == t {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+}
+
+// Removes the ticket from the queue, the
+// caller must hold the lock of the queue.
+func (q *Queue) remove(t *ticket) {
+	q.dequeue(t)
+	delete(q.tickets, t.userId)
+}
+
+// Removes the ticket from the queue with the error, once its
+// wait times out or its user leaves.
+func (q *Queue) expire(t *ticket, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// The ticket could've been matched or canceled
+	// right before its timer fired.
+	if q.tickets[t.userId] != t || t.matched {
+		return
+	}
+
+	q.remove(t)
+	t.resolve(Result{Err: err})
+}
+
+// Creates the conversation of both users and sends it to them.
+func (q *Queue) match(a, b *ticket) {
+	defer q.matches.Done()
+
+	conversation, err := q.conversations.New(q.options.Duration, []int{a.userId, b.userId}, true)
+
+	q.mu.Lock()
+
+	delete(q.tickets, a.userId)
+	delete(q.tickets, b.userId)
+
+	if err == nil {
+		q.pruneLastPartners()
+
+		expiresAt := time.Now().Add(q.lastPartnerTTL)
+		q.lastPartner[a.userId] = partner{userId: b.userId, expiresAt: expiresAt}
+		q.lastPartner[b.userId] = partner{userId: a.userId, expiresAt: expiresAt}
+	}
+
+	q.mu.Unlock()
+
+	if err != nil {
+		q.logger.Error("Create match conversation", err, "userId", a.userId, "partnerId", b.userId)
+
+		a.resolve(Result{Err: err})
+		b.resolve(Result{Err: err})
+		return
+	}
+
+	a.resolve(Result{Conversation: conversation})
+	b.resolve(Result{Conversation: conversation})
+}
+
+// Removes the expired entries of lastPartner, the
+// caller must hold the lock of the queue.
+func (q *Queue) pruneLastPartners() {
+	now := time.Now()
+
+	for userId, last := range q.lastPartner {
+		if !now.Before(last.expiresAt) {
+			delete(q.lastPartner, userId)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package matchmaking
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"golang.org/x/exp/slog"
+)
+
+// Conversation manager that only keeps the created conversations.
+type fakeConversations struct {
+	models.ConversationManager
+
+	mu            sync.Mutex
+	conversations []models.Conversation
+
+	// When not nil, the conversations are
+	// created once it's closed.
+	block chan struct{}
+}
+
+func (f *fakeConversations) New(duration int, participants []int, matched bool) (models.Conversation, error) {
+	if f.block != nil {
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	conversation := models.Conversation{
+		Id:           len(f.conversations) + 1,
+		Duration:     duration,
+		Participants: participants,
+		Matched:      matched,
+	}
+
+	f.conversations = append(f.conversations, conversation)
+
+	return conversation, nil
+}
+
+func newTestQueue(timeout time.Duration) *Queue {
+	return New(
+		&fakeConversations{},
+		Options{Duration: 300, Timeout: timeout},
+		slog.New(slog.NewTextHandler(io.Discard)),
+	)
+}
+
+func receive(t *testing.T, result <-chan Result) Result {
+	t.Helper()
+
+	select {
+	case r := <-result:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("expected a result, got none")
+		return Result{}
+	}
+}
+
+func TestQueueMatch(t *testing.T) {
+	q := newTestQueue(time.Minute)
+
+	first, err := q.Join(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = q.Join(context.Background(), 1)
+	if err != ErrAlreadyQueued {
+		t.Errorf("expected %v, got %v", ErrAlreadyQueued, err)
+	}
+
+	second, err := q.Join(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	a, b := receive(t, first), receive(t, second)
+
+	if a.Err != nil || b.Err != nil {
+		t.Fatalf("expected nil errors, got %v and %v", a.Err, b.Err)
+	}
+
+	if a.Conversation.Id != b.Conversation.Id {
+		t.Errorf("expected the same conversation, got %d and %d", a.Conversation.Id, b.Conversation.Id)
+	}
+
+	if a.Conversation.Duration != 300 {
+		t.Errorf("expected duration=%d, got duration=%d", 300, a.Conversation.Duration)
+	}
+
+	if !a.Conversation.HasParticipant(1) || !a.Conversation.HasParticipant(2) {
+		t.Errorf("expected participants [1 2], got %v", a.Conversation.Participants)
+	}
+
+	if !a.Conversation.Matched {
+		t.Error("expected the conversation to be marked as matched")
+	}
+}
+
+func TestQueueNoRematch(t *testing.T) {
+	q := newTestQueue(time.Minute)
+
+	first, _ := q.Join(context.Background(), 1)
+	second, _ := q.Join(context.Background(), 2)
+	receive(t, first)
+	receive(t, second)
+
+	// Users 1 and 2 must not be paired again,
+	// so both are matched with user 3 and 4.
+	first, _ = q.Join(context.Background(), 1)
+	second, _ = q.Join(context.Background(), 2)
+	third, _ := q.Join(context.Background(), 3)
+	fourth, _ := q.Join(context.Background(), 4)
+
+	for _, result := range []<-chan Result{first, second, third, fourth} {
+		r := receive(t, result)
+		if r.Conversation.HasParticipant(1) && r.Conversation.HasParticipant(2) {
+			t.Errorf("expected users 1 and 2 not to be rematched, got %v", r.Conversation.Participants)
+		}
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	q := newTestQueue(time.Minute)
+
+	err := q.Cancel(1)
+	if err != ErrNotQueued {
+		t.Errorf("expected %v, got %v", ErrNotQueued, err)
+	}
+
+	result, _ := q.Join(context.Background(), 1)
+
+	err = q.Cancel(1)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if r := receive(t, result); r.Err != ErrMatchCanceled {
+		t.Errorf("expected %v, got %v", ErrMatchCanceled, r.Err)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	q := newTestQueue(10 * time.Millisecond)
+
+	result, _ := q.Join(context.Background(), 1)
+
+	if r := receive(t, result); r.Err != ErrMatchTimeout {
+		t.Errorf("expected %v, got %v", ErrMatchTimeout, r.Err)
+	}
+
+	// The user can join again once its wait times out.
+	_, err := q.Join(context.Background(), 1)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	q := newTestQueue(time.Minute)
+
+	result, _ := q.Join(context.Background(), 1)
+
+	q.Close()
+
+	if r := receive(t, result); r.Err != ErrQueueClosed {
+		t.Errorf("expected %v, got %v", ErrQueueClosed, r.Err)
+	}
+
+	// The users can't join once it's closed.
+	_, err := q.Join(context.Background(), 2)
+	if err != ErrQueueClosed {
+		t.Errorf("expected %v, got %v", ErrQueueClosed, err)
+	}
+}
+
+func TestQueueRematchAfterTTL(t *testing.T) {
+	q := newTestQueue(time.Minute)
+	q.lastPartnerTTL = 10 * time.Millisecond
+
+	first, _ := q.Join(context.Background(), 1)
+	second, _ := q.Join(context.Background(), 2)
+	receive(t, first)
+	receive(t, second)
+
+	time.Sleep(20 * time.Millisecond)
+
+	// The entries expired, so they're matched again
+	// and the expired entries are removed.
+	first, _ = q.Join(context.Background(), 1)
+	second, _ = q.Join(context.Background(), 2)
+
+	if r := receive(t, second); !r.Conversation.HasParticipant(1) {
+		t.Errorf("expected users 1 and 2 to be rematched, got %v", r.Conversation.Participants)
+	}
+
+	receive(t, first)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.lastPartner) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(q.lastPartner))
+	}
+}
+
+func TestQueueMatchedCantJoin(t *testing.T) {
+	conversations := &fakeConversations{block: make(chan struct{})}
+	q := New(conversations, Options{Duration: 300, Timeout: time.Minute}, slog.New(slog.NewTextHandler(io.Discard)))
+
+	first, _ := q.Join(context.Background(), 1)
+	second, _ := q.Join(context.Background(), 2)
+
+	// The conversation of the match is being created.
+	_, err := q.Join(context.Background(), 1)
+	if err != ErrAlreadyQueued {
+		t.Errorf("expected %v, got %v", ErrAlreadyQueued, err)
+	}
+
+	err = q.Cancel(2)
+	if err != ErrNotQueued {
+		t.Errorf("expected %v, got %v", ErrNotQueued, err)
+	}
+
+	close(conversations.block)
+
+	receive(t, first)
+	receive(t, second)
+
+	// They can join again once the match is done.
+	_, err = q.Join(context.Background(), 1)
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+}
+
+func TestQueueLeave(t *testing.T) {
+	q := newTestQueue(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	result, _ := q.Join(ctx, 1)
+
+	// The client went away.
+	cancel()
+
+	if r := receive(t, result); r.Err != ErrMatchCanceled {
+		t.Errorf("expected %v, got %v", ErrMatchCanceled, r.Err)
+	}
+
+	// The next user waits instead of being matched with the one that left.
+	second, _ := q.Join(context.Background(), 2)
+
+	select {
+	case r := <-second:
+		t.Errorf("expected no result, got %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
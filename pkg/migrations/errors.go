@@ -0,0 +1,12 @@
+package migrations
+
+import (
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrMigrationFileNotValid = codes.NewCode("migration_file_not_valid")
+	ErrMigrationMissing      = codes.NewCode("migration_missing")
+	ErrNoMigrationApplied    = codes.NewCode("no_migration_applied")
+	ErrSchemaOutdated        = codes.NewCode("schema_outdated")
+)
@@ -0,0 +1,340 @@
+// Package migrations applies and reverts numbered SQL migrations,
+// keeping track of the applied ones in the table `Schema_Migration`.
+//
+// A migration is made of two files named `<version>_<name>.up.sql`
+// and `<version>_<name>.down.sql`, the scripts can be split in
+// batches with lines containing only `GO`, like SQL Server tools do.
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	getAppliedMigrations = `
+	SELECT [Version], [Applied_At]
+	FROM [Schema_Migration];
+	`
+
+	insertAppliedMigration = `
+	INSERT INTO [Schema_Migration] ([Version], [Name], [Applied_At])
+	VALUES(@Version, @Name, @Applied_At);
+	`
+
+	deleteAppliedMigration = `
+	DELETE FROM [Schema_Migration]
+	WHERE [Version] = @Version;
+	`
+)
+
+var (
+	fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	batchSeparator  = regexp.MustCompile(`(?im)^\s*GO\s*$`)
+)
+
+// Migration represents a change of the schema.
+type Migration struct {
+	Version int
+	Name    string
+
+	// Batches of the scripts applying and reverting the change.
+	Up   []string
+	Down []string
+}
+
+// Status represents whether a migration has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// Migrator applies the migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+
+	// Statement creating the table `Schema_Migration`
+	// if it doesn't exist, it depends on the database.
+	createTable string
+}
+
+// Creates a migrator of the database with the migrations stored in the root
+// of fsys, createTable is the statement creating the tracking table with the
+// columns `Version` (integer primary key), `Name` and `Applied_At`.
+func New(db *sql.DB, fsys fs.FS, createTable string) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		createTable: createTable,
+	}, nil
+}
+
+// Reads the migrations stored in the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrMigrationFileNotValid, entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		name, direction := match[2], match[3]
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrMigrationFileNotValid, version)
+		}
+
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if direction == "up" {
+			migration.Up = splitBatches(string(script))
+		} else {
+			migration.Down = splitBatches(string(script))
+		}
+	}
+
+	migrations := []Migration{}
+
+	for _, migration := range byVersion {
+		if migration.Up == nil || migration.Down == nil {
+			return nil, fmt.Errorf("%w: version %d needs up and down files", ErrMigrationFileNotValid, migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationMissing, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Splits the script in the batches separated by lines containing only `GO`.
+func splitBatches(script string) []string {
+	batches := []string{}
+
+	for _, batch := range batchSeparator.Split(script, -1) {
+		if strings.TrimSpace(batch) != "" {
+			batches = append(batches, batch)
+		}
+	}
+
+	return batches
+}
+
+// Returns the version of the newest migration.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Returns the time each applied migration was applied by version.
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	_, err := m.db.Exec(m.createTable)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(getAppliedMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// Returns the version of the newest applied migration,
+// it's zero if no migrations have been applied.
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// Returns the status of every migration.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+
+	for _, migration := range m.migrations {
+		status := Status{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Applies the pending migrations in order, each one inside its
+// own transaction, and returns the ones that were applied.
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(migration.Up, insertAppliedMigration,
+			sql.Named("Version", migration.Version),
+			sql.Named("Name", migration.Name),
+			sql.Named("Applied_At", time.Now().UTC()),
+		)
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Reverts the newest applied migration and returns it.
+func (m *Migrator) Down() (Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return Migration{}, err
+	}
+
+	if version == 0 {
+		return Migration{}, ErrNoMigrationApplied
+	}
+
+	if version > m.Latest() {
+		return Migration{}, fmt.Errorf("%w: version %d", ErrMigrationMissing, version)
+	}
+
+	migration := m.migrations[version-1]
+
+	err = m.run(migration.Down, deleteAppliedMigration,
+		sql.Named("Version", migration.Version),
+	)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+	}
+
+	return migration, nil
+}
+
+// Runs the batches and the statement updating the tracking table in a transaction.
+func (m *Migrator) run(batches []string, track string, trackArgs ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, batch := range batches {
+		_, err := tx.Exec(batch)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(track, trackArgs...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Returns an error wrapping `ErrSchemaOutdated` if there
+// are migrations that have not been applied.
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if version < m.Latest() {
+		return fmt.Errorf(
+			"%w: the database is at version %d but version %d is required, run `migrate up`",
+			ErrSchemaOutdated, version, m.Latest(),
+		)
+	}
+
+	return nil
+}
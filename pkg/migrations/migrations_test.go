@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const createTestTable = `
+CREATE TABLE IF NOT EXISTS [Schema_Migration] (
+    [Version] INTEGER PRIMARY KEY,
+    [Name] TEXT NOT NULL,
+    [Applied_At] DATETIME NOT NULL
+);
+`
+
+var testMigrations = fstest.MapFS{
+	"0001_create_foo.up.sql":   {Data: []byte("CREATE TABLE [Foo] ([Id] INTEGER);\nGO\nCREATE TABLE [Baz] ([Id] INTEGER);")},
+	"0001_create_foo.down.sql": {Data: []byte("DROP TABLE [Foo];\ngo\nDROP TABLE [Baz];")},
+	"0002_create_bar.up.sql":   {Data: []byte("CREATE TABLE [Bar] ([Id] INTEGER);")},
+	"0002_create_bar.down.sql": {Data: []byte("DROP TABLE [Bar];")},
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testMigrations)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(migrations) != 2 || migrations[0].Name != "create_foo" || migrations[1].Version != 2 {
+		t.Fatalf("expected migrations create_foo and create_bar, got %+v", migrations)
+	}
+
+	if len(migrations[0].Up) != 2 || len(migrations[0].Down) != 2 {
+		t.Errorf("expected 2 batches per script, got up=%d down=%d", len(migrations[0].Up), len(migrations[0].Down))
+	}
+
+	_, err = Load(fstest.MapFS{
+		"0002_create_bar.up.sql":   {Data: []byte("")},
+		"0002_create_bar.down.sql": {Data: []byte("")},
+	})
+	if !errors.Is(err, ErrMigrationMissing) {
+		t.Errorf("expected %v, got %v", ErrMigrationMissing, err)
+	}
+
+	_, err = Load(fstest.MapFS{"create_bar.sql": {Data: []byte("")}})
+	if !errors.Is(err, ErrMigrationFileNotValid) {
+		t.Errorf("expected %v, got %v", ErrMigrationFileNotValid, err)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := New(db, testMigrations, createTestTable)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = migrator.Check()
+	if !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("expected %v, got %v", ErrSchemaOutdated, err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("expected 2 applied migrations and nil error, got %d and %v", len(applied), err)
+	}
+
+	err = migrator.Check()
+	if err != nil {
+		t.Errorf("expected nil error, got %v", err)
+	}
+
+	reverted, err := migrator.Down()
+	if err != nil || reverted.Version != 2 {
+		t.Fatalf("expected version 2 reverted and nil error, got %d and %v", reverted.Version, err)
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("expected only version 1 to be applied, got %+v", statuses)
+	}
+
+	_, err = db.Exec("SELECT * FROM [Bar]")
+	if err == nil {
+		t.Errorf("expected table Bar to be dropped")
+	}
+
+	migrator.Down()
+
+	_, err = migrator.Down()
+	if err != ErrNoMigrationApplied {
+		t.Errorf("expected %v, got %v", ErrNoMigrationApplied, err)
+	}
+}
@@ -2,7 +2,26 @@ package models
 
 const (
 	UserNameMaxLength          = 40
-	UserPasswordLength         = 60
+	UserPasswordLength         = 255
 	UserProfilePictureIdLength = 36
 	UserBirthdateFormat        = "2006-01-02"
+
+	// Values stored in the row of a user deleted with
+	// `DeletionPolicyTombstone`, the names starting with
+	// the prefix are reserved for the deleted users.
+	DeletedUserNamePrefix = "deleted_user_"
+	DeletedUserNameFormat = DeletedUserNamePrefix + "%d"
+	DeletedUserBirthdate  = "1900-01-01"
+
+	// The duration of a conversation is stored
+	// in seconds as a SMALLINT, a duration of zero
+	// means the conversation has no time limit.
+	ConversationDurationMax = 32767
+
+	MessageContentMaxLength = 300
+
+	// Number of messages returned by a history
+	// page when no limit (or a bad one) is requested.
+	MessageHistoryDefaultLimit = 50
+	MessageHistoryMaxLimit     = 100
 )
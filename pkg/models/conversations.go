@@ -0,0 +1,18 @@
+package models
+
+import "github.com/Edwing123/udem-chat-app/pkg/validations/validator"
+
+// Checks the duration of a conversation to be created.
+func CheckConversationDuration(duration int) *validator.Validator {
+	v := validator.New()
+
+	v.Check(validator.Between(duration, 0, ConversationDurationMax), "duration", ErrConversationDurationNotValid)
+
+	return v
+}
+
+// Validates the duration of a conversation, it's shared
+// by the implementations of `ConversationManager`.
+func ValidateConversationDuration(duration int) error {
+	return CheckConversationDuration(duration).Err()
+}
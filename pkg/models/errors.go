@@ -1,24 +1,45 @@
 package models
 
-import "github.com/Edwing123/udem-chat-app/pkg/codes"
+import (
+	"net/http"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
 
 var (
 	// User errors.
-	ErrUserNameExists                     = codes.NewCode("user_name_exists")
+	ErrUserNameExists                     = codes.NewCodeWithStatus("user_name_exists", http.StatusConflict)
 	ErrUserNameEmpty                      = codes.NewCode("user_name_empty")
 	ErrUserPasswordEmpty                  = codes.NewCode("user_password_empty")
 	ErrUserBirthdateEmpty                 = codes.NewCode("user_birthdate_empty")
 	ErrUserBirthdateBadFormat             = codes.NewCode("user_birthdate_bad_format")
 	ErrUserNameExceedsMaxLength           = codes.NewCode("user_name_exceeds_max_length")
+	ErrUserNameReserved                   = codes.NewCode("user_name_reserved")
 	ErrUserPasswordNotValidLength         = codes.NewCode("user_password_not_valid_length")
 	ErrUserProfilePictureIdNotValidLength = codes.NewCode("user_profile_picture_id_not_valid_length")
+	ErrUserDeletionPolicyNotValid         = codes.NewCodeWithStatus("user_deletion_policy_not_valid", http.StatusInternalServerError)
+
+	// Conversation errors.
+	ErrConversationDurationNotValid = codes.NewCode("conversation_duration_not_valid")
+	ErrConversationEnded            = codes.NewCodeWithStatus("conversation_ended", http.StatusConflict)
+	ErrConversationExpired          = codes.NewCodeWithStatus("conversation_expired", http.StatusConflict)
+	ErrParticipantExists            = codes.NewCodeWithStatus("participant_exists", http.StatusConflict)
+	ErrNotParticipant               = codes.NewCodeWithStatus("not_participant", http.StatusForbidden)
+	ErrParticipantNotSelf           = codes.NewCodeWithStatus("participant_not_self", http.StatusForbidden)
+	ErrConversationMatched          = codes.NewCodeWithStatus("conversation_matched", http.StatusForbidden)
+
+	// Message errors.
+	ErrMessageContentEmpty              = codes.NewCode("message_content_empty")
+	ErrMessageContentExceedsMaxLength   = codes.NewCode("message_content_exceeds_max_length")
+	ErrMessageHistoryCursorNotValid     = codes.NewCode("message_history_cursor_not_valid")
+	ErrMessageHistoryCursorsConflicting = codes.NewCode("message_history_cursors_conflicting")
 
 	// Authentication and password change errors.
-	ErrPasswordMismatch = codes.NewCode("password_mismatch")
-	ErrLoginFail        = codes.NewCode("login_fail")
+	ErrPasswordMismatch = codes.NewCodeWithStatus("password_mismatch", http.StatusUnauthorized)
+	ErrLoginFail        = codes.NewCodeWithStatus("login_fail", http.StatusUnauthorized)
 
 	// Generic database errors.
-	ErrNoRecords          = codes.NewCode("no_records")
-	ErrDatabaseServerFail = codes.NewCode("database_server_fail")
+	ErrNoRecords          = codes.NewCodeWithStatus("no_records", http.StatusNotFound)
+	ErrDatabaseServerFail = codes.NewCodeWithStatus("database_server_fail", http.StatusInternalServerError)
 	ErrNoUpdates          = codes.NewCode("no_updates_performed")
 )
@@ -6,4 +6,21 @@ type UserManager interface {
 	Login(user User) (int, error)
 	Update(id int, user User) (User, string, error)
 	ChangePassword(id int, currentPass, newPass string) error
+	Delete(id int, password string, policy DeletionPolicy) (string, error)
+}
+
+type ConversationManager interface {
+	New(duration int, participants []int, matched bool) (Conversation, error)
+	Get(id int) (Conversation, error)
+	ListForUser(userId int) ([]Conversation, error)
+	ListExpiring() ([]Conversation, error)
+	AddParticipant(id int, userId int) error
+	RemoveParticipant(id int, userId int) error
+	End(id int) error
+}
+
+type MessageManager interface {
+	New(message Message) (Message, error)
+	Get(id int) (Message, error)
+	History(conversationId int, query HistoryQuery) (MessagesPage, error)
 }
@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+)
+
+type ConversationManager struct {
+	mu sync.RWMutex
+
+	conversations map[int]models.Conversation
+
+	lastId int
+
+	// Used to check the participants exist.
+	users *UserManager
+}
+
+// Returns a copy of the conversation, so the callers
+// can't modify the participants stored by the manager.
+func copyConversation(conversation models.Conversation) models.Conversation {
+	conversation.Participants = append([]int{}, conversation.Participants...)
+	conversation.Remaining = conversation.RemainingSeconds(time.Now())
+	return conversation
+}
+
+// Reports whether the user identified by userId has
+// joined the conversation identified by id.
+func (cm *ConversationManager) hasParticipant(id int, userId int) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return cm.conversations[id].HasParticipant(userId)
+}
+
+func (cm *ConversationManager) New(duration int, participants []int, matched bool) (models.Conversation, error) {
+	err := models.ValidateConversationDuration(duration)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	conversation := models.Conversation{
+		CreatedAt:    time.Now().UTC(),
+		Duration:     duration,
+		Participants: []int{},
+		Matched:      matched,
+	}
+
+	for _, userId := range participants {
+		// Ignore repeated participants.
+		if conversation.HasParticipant(userId) {
+			continue
+		}
+
+		if !cm.users.exists(userId) {
+			return models.Conversation{}, models.ErrNoRecords
+		}
+
+		conversation.Participants = append(conversation.Participants, userId)
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.lastId++
+	conversation.Id = cm.lastId
+	cm.conversations[conversation.Id] = conversation
+
+	return copyConversation(conversation), nil
+}
+
+func (cm *ConversationManager) Get(id int) (models.Conversation, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	conversation, ok := cm.conversations[id]
+	if !ok {
+		return models.Conversation{}, models.ErrNoRecords
+	}
+
+	return copyConversation(conversation), nil
+}
+
+func (cm *ConversationManager) ListForUser(userId int) ([]models.Conversation, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	conversations := []models.Conversation{}
+
+	for _, conversation := range cm.conversations {
+		if conversation.HasParticipant(userId) {
+			conversations = append(conversations, copyConversation(conversation))
+		}
+	}
+
+	// The most recent ones come first.
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].Id > conversations[j].Id
+	})
+
+	return conversations, nil
+}
+
+func (cm *ConversationManager) ListExpiring() ([]models.Conversation, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	conversations := []models.Conversation{}
+
+	for _, conversation := range cm.conversations {
+		if conversation.EndedAt == nil && conversation.Duration > 0 {
+			conversation := copyConversation(conversation)
+			conversation.Participants = nil
+			conversations = append(conversations, conversation)
+		}
+	}
+
+	return conversations, nil
+}
+
+func (cm *ConversationManager) AddParticipant(id int, userId int) error {
+	if !cm.users.exists(userId) {
+		return models.ErrNoRecords
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	conversation, ok := cm.conversations[id]
+	if !ok {
+		return models.ErrNoRecords
+	}
+
+	if conversation.EndedAt != nil {
+		return models.ErrConversationEnded
+	}
+
+	if conversation.HasParticipant(userId) {
+		return models.ErrParticipantExists
+	}
+
+	conversation.Participants = append(conversation.Participants, userId)
+	cm.conversations[id] = conversation
+
+	return nil
+}
+
+func (cm *ConversationManager) RemoveParticipant(id int, userId int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	conversation, ok := cm.conversations[id]
+	if !ok {
+		return models.ErrNoRecords
+	}
+
+	if !conversation.HasParticipant(userId) {
+		return models.ErrNotParticipant
+	}
+
+	participants := []int{}
+
+	for _, participantId := range conversation.Participants {
+		if participantId != userId {
+			participants = append(participants, participantId)
+		}
+	}
+
+	conversation.Participants = participants
+	cm.conversations[id] = conversation
+
+	return nil
+}
+
+func (cm *ConversationManager) End(id int) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	conversation, ok := cm.conversations[id]
+	if !ok {
+		return models.ErrNoRecords
+	}
+
+	if conversation.EndedAt != nil {
+		return models.ErrConversationEnded
+	}
+
+	endedAt := time.Now().UTC()
+	conversation.EndedAt = &endedAt
+	cm.conversations[id] = conversation
+
+	return nil
+}
+
+// Removes the user identified by userId from every
+// conversation, it's used when the user is deleted.
+func (cm *ConversationManager) removeUser(userId int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	for id, conversation := range cm.conversations {
+		if !conversation.HasParticipant(userId) {
+			continue
+		}
+
+		participants := []int{}
+
+		for _, participantId := range conversation.Participants {
+			if participantId != userId {
+				participants = append(participants, participantId)
+			}
+		}
+
+		conversation.Participants = participants
+		cm.conversations[id] = conversation
+	}
+}
@@ -0,0 +1,40 @@
+// Package memory implements `models.Database` keeping the data
+// in memory, it's meant for tests and local development, the data
+// is lost once the process exits.
+package memory
+
+import (
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"golang.org/x/exp/slog"
+)
+
+// Creates the in-memory implementation of `models.Database`,
+// the passwords of the users are hashed with hasher.
+func New(hasher *hashing.Manager, logger *slog.Logger) models.Database {
+	userManager := &UserManager{
+		users:  map[int]models.User{},
+		names:  map[string]int{},
+		hasher: hasher,
+		logger: logger,
+	}
+
+	conversationManager := &ConversationManager{
+		conversations: map[int]models.Conversation{},
+		users:         userManager,
+	}
+
+	messageManager := &MessageManager{
+		messages:      map[int][]models.Message{},
+		conversations: conversationManager,
+	}
+
+	userManager.conversations = conversationManager
+	userManager.messages = messageManager
+
+	return models.Database{
+		UserManager:         userManager,
+		ConversationManager: conversationManager,
+		MessageManager:      messageManager,
+	}
+}
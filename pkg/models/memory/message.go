@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+)
+
+type MessageManager struct {
+	mu sync.RWMutex
+
+	// Messages of each conversation, from the oldest to the newest.
+	messages map[int][]models.Message
+
+	lastId int
+
+	// Used to check the authors joined the conversation.
+	conversations *ConversationManager
+}
+
+func (mm *MessageManager) New(message models.Message) (models.Message, error) {
+	err := models.ValidateMessageContent(message.Content)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	if !mm.conversations.hasParticipant(message.ConversationId, message.UserId) {
+		return models.Message{}, models.ErrNotParticipant
+	}
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	mm.lastId++
+	message.Id = mm.lastId
+	message.CreatedAt = time.Now().UTC()
+
+	mm.messages[message.ConversationId] = append(mm.messages[message.ConversationId], message)
+
+	return message, nil
+}
+
+func (mm *MessageManager) Get(id int) (models.Message, error) {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	for _, messages := range mm.messages {
+		for _, message := range messages {
+			if message.Id == id {
+				return message, nil
+			}
+		}
+	}
+
+	return models.Message{}, models.ErrNoRecords
+}
+
+func (mm *MessageManager) History(conversationId int, query models.HistoryQuery) (models.MessagesPage, error) {
+	if query.Before != "" && query.After != "" {
+		return models.MessagesPage{}, models.ErrMessageHistoryCursorsConflicting
+	}
+
+	var cursorId int
+
+	if query.Before != "" || query.After != "" {
+		cursor := query.Before
+		if query.After != "" {
+			cursor = query.After
+		}
+
+		id, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return models.MessagesPage{}, err
+		}
+
+		cursorId = id
+	}
+
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+
+	conversationMessages := mm.messages[conversationId]
+
+	// Fetch one more message than the limit to
+	// know whether there are more messages.
+	limit := query.PageLimit() + 1
+	messages := []models.Message{}
+
+	if query.After != "" {
+		for _, message := range conversationMessages {
+			if len(messages) == limit {
+				break
+			}
+
+			if message.Id > cursorId {
+				messages = append(messages, message)
+			}
+		}
+	} else {
+		for i := len(conversationMessages) - 1; i >= 0; i-- {
+			if len(messages) == limit {
+				break
+			}
+
+			message := conversationMessages[i]
+
+			if query.Before == "" || message.Id < cursorId {
+				messages = append(messages, message)
+			}
+		}
+	}
+
+	return models.NewMessagesPage(query, messages), nil
+}
+
+// Removes the messages sent by the user identified
+// by userId, it's used when the user is deleted.
+func (mm *MessageManager) removeUser(userId int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	for conversationId, messages := range mm.messages {
+		kept := []models.Message{}
+
+		for _, message := range messages {
+			if message.UserId != userId {
+				kept = append(kept, message)
+			}
+		}
+
+		mm.messages[conversationId] = kept
+	}
+}
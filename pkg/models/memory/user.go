@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"golang.org/x/exp/slog"
+)
+
+type UserManager struct {
+	mu sync.RWMutex
+
+	// Users by id, the password is stored hashed.
+	users map[int]models.User
+
+	// User ids by name, the names are compared without
+	// case, like the default collation of SQL Server does.
+	names map[string]int
+
+	lastId int
+
+	// Used to remove the data of the deleted users.
+	conversations *ConversationManager
+	messages      *MessageManager
+
+	hasher *hashing.Manager
+	logger *slog.Logger
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// Reports whether the user identified by id exists.
+func (um *UserManager) exists(id int) bool {
+	um.mu.RLock()
+	defer um.mu.RUnlock()
+
+	_, ok := um.users[id]
+	return ok
+}
+
+func (um *UserManager) New(user models.User) error {
+	// Validate user input.
+	err := models.ValidateNewUser(user)
+	if err != nil {
+		return err
+	}
+
+	// Hash the password.
+	hashedPassword, err := um.hasher.HashPassword([]byte(user.Password))
+	if err != nil {
+		um.logger.Error("Hash password", err)
+		return hashing.ErrPasswordHashingFail
+	}
+
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	if _, ok := um.names[nameKey(user.Name)]; ok {
+		return models.ErrUserNameExists
+	}
+
+	um.lastId++
+
+	um.users[um.lastId] = models.User{
+		Id:        um.lastId,
+		Name:      user.Name,
+		Password:  string(hashedPassword),
+		Birthdate: user.Birthdate,
+	}
+	um.names[nameKey(user.Name)] = um.lastId
+
+	return nil
+}
+
+func (um *UserManager) Get(id int) (models.User, error) {
+	um.mu.RLock()
+	defer um.mu.RUnlock()
+
+	user, ok := um.users[id]
+	if !ok {
+		return models.User{}, models.ErrNoRecords
+	}
+
+	user.Password = ""
+
+	return user, nil
+}
+
+func (um *UserManager) Login(user models.User) (int, error) {
+	// Validate user input.
+	err := models.ValidateLogin(user)
+	if err != nil {
+		return 0, err
+	}
+
+	um.mu.RLock()
+	id, ok := um.names[nameKey(user.Name)]
+	hashedPassword := um.users[id].Password
+	um.mu.RUnlock()
+
+	if !ok {
+		return 0, models.ErrLoginFail
+	}
+
+	isPasswordValid, outdated := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(user.Password))
+	if !isPasswordValid {
+		return 0, models.ErrLoginFail
+	}
+
+	if outdated {
+		um.rehash(id, hashedPassword, user.Password)
+	}
+
+	return id, nil
+}
+
+// Replaces the outdated hash of the password of the user identified
+// by id, failures are only logged since the outdated hash still works.
+//
+// The hash is only replaced if it didn't change in the meantime,
+// otherwise a concurrent password change could be undone.
+func (um *UserManager) rehash(id int, hashedPassword string, password string) {
+	newHashedPass, err := um.hasher.HashPassword([]byte(password))
+	if err != nil {
+		um.logger.Error("Rehash user password", err, "userId", id)
+		return
+	}
+
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	user, ok := um.users[id]
+	if !ok || user.Password != hashedPassword {
+		return
+	}
+
+	user.Password = string(newHashedPass)
+	um.users[id] = user
+}
+
+func (um *UserManager) Update(id int, user models.User) (models.User, string, error) {
+	err := models.ValidateUserUpdate(user)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	// If all update-able fields are empty, then return an error to notify
+	// that no updates were performed.
+	if user.Name == "" && user.Birthdate == "" && user.ProfilePictureId == "" {
+		return models.User{}, "", models.ErrNoUpdates
+	}
+
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	current, ok := um.users[id]
+	if !ok {
+		return models.User{}, "", models.ErrNoRecords
+	}
+
+	var oldImageId string
+
+	if user.Name != "" {
+		ownerId, ok := um.names[nameKey(user.Name)]
+		if ok && ownerId != id {
+			return models.User{}, "", models.ErrUserNameExists
+		}
+
+		delete(um.names, nameKey(current.Name))
+		um.names[nameKey(user.Name)] = id
+		current.Name = user.Name
+	}
+
+	if user.Birthdate != "" {
+		current.Birthdate = user.Birthdate
+	}
+
+	if user.ProfilePictureId != "" {
+		oldImageId = current.ProfilePictureId
+		current.ProfilePictureId = user.ProfilePictureId
+	}
+
+	um.users[id] = current
+
+	return user, oldImageId, nil
+}
+
+func (um *UserManager) ChangePassword(id int, currentPass, newPass string) error {
+	if currentPass == "" || newPass == "" {
+		return models.ErrUserPasswordEmpty
+	}
+
+	um.mu.RLock()
+	user, ok := um.users[id]
+	um.mu.RUnlock()
+
+	if !ok {
+		return models.ErrNoRecords
+	}
+
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(user.Password), []byte(currentPass))
+	if !isValidPassword {
+		return models.ErrPasswordMismatch
+	}
+
+	newHashedPass, err := um.hasher.HashPassword([]byte(newPass))
+	if err != nil {
+		return hashing.ErrPasswordHashingFail
+	}
+
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	user, ok = um.users[id]
+	if !ok {
+		return models.ErrNoRecords
+	}
+
+	user.Password = string(newHashedPass)
+	um.users[id] = user
+
+	return nil
+}
+
+// Deletes the account of the user identified by id once its password
+// is confirmed, the data of the user is handled according to policy.
+//
+// It returns the id of the profile picture of the user (if any),
+// so the caller can archive it.
+func (um *UserManager) Delete(id int, password string, policy models.DeletionPolicy) (string, error) {
+	if password == "" {
+		return "", models.ErrUserPasswordEmpty
+	}
+
+	if !policy.IsValid() {
+		return "", models.ErrUserDeletionPolicyNotValid
+	}
+
+	um.mu.Lock()
+	defer um.mu.Unlock()
+
+	user, ok := um.users[id]
+	if !ok {
+		return "", models.ErrNoRecords
+	}
+
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(user.Password), []byte(password))
+	if !isValidPassword {
+		return "", models.ErrPasswordMismatch
+	}
+
+	tombstone := models.TombstoneUser(id)
+
+	// Like the unique constraint of the databases, the name of the
+	// tombstone could've been taken before it was reserved.
+	if policy == models.DeletionPolicyTombstone {
+		if owner, ok := um.names[nameKey(tombstone.Name)]; ok && owner != id {
+			return "", models.ErrUserNameExists
+		}
+	}
+
+	delete(um.names, nameKey(user.Name))
+
+	switch policy {
+	case models.DeletionPolicyDelete:
+		um.messages.removeUser(id)
+		um.conversations.removeUser(id)
+		delete(um.users, id)
+
+	case models.DeletionPolicyTombstone:
+		um.users[id] = tombstone
+		um.names[nameKey(tombstone.Name)] = id
+	}
+
+	return user.ProfilePictureId, nil
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/exp/slog"
+)
+
+func TestUserManager(t *testing.T) {
+	database := New(hashing.Default(), slog.New(slog.NewTextHandler(io.Discard)))
+	users := database.UserManager
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := users.New(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// Names are compared without case.
+	err = users.New(models.User{Name: "EDWIN", Password: "foo", Birthdate: "2000-01-01"})
+	if err != models.ErrUserNameExists {
+		t.Errorf("expected %v, got %v", models.ErrUserNameExists, err)
+	}
+
+	// The names of the deleted users are reserved.
+	err = users.New(models.User{Name: "Deleted_User_7", Password: "foo", Birthdate: "2000-01-01"})
+	if err != models.ErrUserNameReserved {
+		t.Errorf("expected %v, got %v", models.ErrUserNameReserved, err)
+	}
+
+	err = users.New(models.User{Name: "foo", Password: "foo", Birthdate: "01/01/2000"})
+	if err != models.ErrUserBirthdateBadFormat {
+		t.Errorf("expected %v, got %v", models.ErrUserBirthdateBadFormat, err)
+	}
+
+	id, err := users.Login(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = users.Login(models.User{Name: user.Name, Password: "wrong"})
+	if err != models.ErrLoginFail {
+		t.Errorf("expected %v, got %v", models.ErrLoginFail, err)
+	}
+
+	_, _, err = users.Update(id, models.User{})
+	if err != models.ErrNoUpdates {
+		t.Errorf("expected %v, got %v", models.ErrNoUpdates, err)
+	}
+
+	_, _, err = users.Update(id, models.User{Name: "deleted_user_7"})
+	if err != models.ErrUserNameReserved {
+		t.Errorf("expected %v, got %v", models.ErrUserNameReserved, err)
+	}
+
+	err = users.ChangePassword(id, "wrong", "password#456")
+	if err != models.ErrPasswordMismatch {
+		t.Errorf("expected %v, got %v", models.ErrPasswordMismatch, err)
+	}
+
+	stored, err := users.Get(id)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if stored.Name != user.Name || stored.Password != "" {
+		t.Errorf("expected name=%q without password, got name=%q password=%q", user.Name, stored.Name, stored.Password)
+	}
+}
+
+func TestUserManagerRehash(t *testing.T) {
+	database := New(hashing.New(hashing.Bcrypt{Cost: bcrypt.MinCost}), slog.New(slog.NewTextHandler(io.Discard)))
+	users := database.UserManager.(*UserManager)
+
+	user := models.User{
+		Name:      "edwin",
+		Password:  "password#123",
+		Birthdate: "2000-01-01",
+	}
+
+	err := users.New(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	id := users.names[nameKey(user.Name)]
+	outdatedHash := users.users[id].Password
+
+	// The preferred algorithm changes after the user signed up.
+	users.hasher = hashing.New(hashing.Argon2id{Time: 1, Memory: 1024, Threads: 1})
+
+	_, err = users.Login(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	rehashed := users.users[id].Password
+	if rehashed == outdatedHash {
+		t.Fatal("expected the outdated hash to be replaced")
+	}
+
+	ok, outdated := users.hasher.VerifyPassword([]byte(rehashed), []byte(user.Password))
+	if !ok || outdated {
+		t.Errorf("expected ok=true outdated=false, got ok=%v outdated=%v", ok, outdated)
+	}
+
+	// The new hash is used from now on.
+	_, err = users.Login(user)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if users.users[id].Password != rehashed {
+		t.Error("expected the up to date hash to be kept")
+	}
+}
+
+func TestUserManagerTombstoneNameTaken(t *testing.T) {
+	database := New(hashing.Default(), slog.New(slog.NewTextHandler(io.Discard)))
+	users := database.UserManager.(*UserManager)
+
+	for _, name := range []string{"edwin", "carlos"} {
+		err := users.New(models.User{Name: name, Password: "password#123", Birthdate: "2000-01-01"})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	edwinId := users.names[nameKey("edwin")]
+	carlosId := users.names[nameKey("carlos")]
+
+	// Carlos took the name of the tombstone of Edwin before it was reserved.
+	tombstoneName := fmt.Sprintf(models.DeletedUserNameFormat, edwinId)
+	users.names[nameKey(tombstoneName)] = carlosId
+
+	_, err := users.Delete(edwinId, "password#123", models.DeletionPolicyTombstone)
+	if err != models.ErrUserNameExists {
+		t.Errorf("expected %v, got %v", models.ErrUserNameExists, err)
+	}
+
+	if users.names[nameKey(tombstoneName)] != carlosId || users.names[nameKey("edwin")] != edwinId {
+		t.Error("expected the names to be kept")
+	}
+}
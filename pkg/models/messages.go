@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/base64"
+	"strconv"
+
+	"github.com/Edwing123/udem-chat-app/pkg/validations/validator"
+)
+
+// Creates the opaque cursor pointing to the message identified by id.
+func EncodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// Returns the id of the message the cursor points to, an error
+// is returned if the cursor was not created by `EncodeCursor`.
+func DecodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrMessageHistoryCursorNotValid
+	}
+
+	id, err := strconv.Atoi(string(decoded))
+	if err != nil || id <= 0 {
+		return 0, ErrMessageHistoryCursorNotValid
+	}
+
+	return id, nil
+}
+
+// Normalizes the limit of the query to the allowed range.
+func (q HistoryQuery) PageLimit() int {
+	if q.Limit <= 0 {
+		return MessageHistoryDefaultLimit
+	}
+
+	if q.Limit > MessageHistoryMaxLimit {
+		return MessageHistoryMaxLimit
+	}
+
+	return q.Limit
+}
+
+// Checks the content of a message to be sent.
+func CheckMessageContent(content string) *validator.Validator {
+	v := validator.New()
+
+	v.Check(validator.NotBlank(content), "content", ErrMessageContentEmpty)
+	v.Check(validator.MaxChars(content, MessageContentMaxLength), "content", ErrMessageContentExceedsMaxLength)
+
+	return v
+}
+
+// Validates the content of a message, it's shared
+// by the implementations of `MessageManager`.
+func ValidateMessageContent(content string) error {
+	return CheckMessageContent(content).Err()
+}
+
+// Builds the page out of the messages fetched for the query, the
+// messages must be sorted from the newest to the oldest, unless the
+// query has the cursor After set, in which case the messages must be
+// sorted from the oldest to the newest. Up to `q.PageLimit() + 1`
+// messages should be fetched, the extra message is used to know
+// whether there are more messages after the page.
+func NewMessagesPage(q HistoryQuery, messages []Message) MessagesPage {
+	limit := q.PageLimit()
+	hasMore := len(messages) > limit
+
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	// Sort the messages from the oldest to the newest.
+	if q.After == "" {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	page := MessagesPage{
+		Messages: messages,
+		After:    q.After,
+	}
+
+	if len(messages) == 0 {
+		page.Messages = []Message{}
+		return page
+	}
+
+	oldest := messages[0]
+	newest := messages[len(messages)-1]
+
+	page.After = EncodeCursor(newest.Id)
+
+	// When paginating forward there're always older
+	// messages, at least the one the cursor After points to.
+	if q.After != "" || hasMore {
+		page.Before = EncodeCursor(oldest.Id)
+	}
+
+	return page
+}
@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	cursor := EncodeCursor(42)
+
+	id, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if id != 42 {
+		t.Errorf("expected id=%d, got id=%d", 42, id)
+	}
+
+	for _, cursor := range []string{"", "***", EncodeCursor(0), "Zm9v"} {
+		_, err := DecodeCursor(cursor)
+		if err != ErrMessageHistoryCursorNotValid {
+			t.Errorf("expected %v for cursor %q, got %v", ErrMessageHistoryCursorNotValid, cursor, err)
+		}
+	}
+}
+
+func TestNewMessagesPage(t *testing.T) {
+	// Messages from the newest to the oldest, one more than the limit.
+	messages := []Message{{Id: 5}, {Id: 4}, {Id: 3}}
+
+	page := NewMessagesPage(HistoryQuery{Limit: 2}, messages)
+
+	if len(page.Messages) != 2 || page.Messages[0].Id != 4 || page.Messages[1].Id != 5 {
+		t.Fatalf("expected messages [4 5], got %v", page.Messages)
+	}
+
+	if page.Before != EncodeCursor(4) {
+		t.Errorf("expected before=%q, got before=%q", EncodeCursor(4), page.Before)
+	}
+
+	if page.After != EncodeCursor(5) {
+		t.Errorf("expected after=%q, got after=%q", EncodeCursor(5), page.After)
+	}
+
+	// The oldest page has no before cursor.
+	page = NewMessagesPage(HistoryQuery{Before: EncodeCursor(4), Limit: 2}, []Message{{Id: 3}})
+
+	if page.Before != "" {
+		t.Errorf("expected empty before, got before=%q", page.Before)
+	}
+
+	// Polling for new messages keeps the after cursor.
+	page = NewMessagesPage(HistoryQuery{After: EncodeCursor(5)}, nil)
+
+	if page.After != EncodeCursor(5) || len(page.Messages) != 0 {
+		t.Errorf("expected after=%q and no messages, got after=%q and %v", EncodeCursor(5), page.After, page.Messages)
+	}
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"math"
+	"time"
+)
+
 type User struct {
 	Id               int    `json:"id,omitempty"`
 	Name             string `json:"name,omitempty"`
@@ -8,6 +13,114 @@ type User struct {
 	ProfilePictureId string `json:"profilePictureId,omitempty"`
 }
 
+type Conversation struct {
+	Id        int       `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Duration of the conversation in seconds.
+	Duration int `json:"duration"`
+
+	// The time the conversation was ended,
+	// it's nil while the conversation is active.
+	EndedAt *time.Time `json:"endedAt,omitempty"`
+
+	// Ids of the users that joined the conversation.
+	Participants []int `json:"participants"`
+
+	// Whether the matchmaking queue created the conversation,
+	// the participants of those conversations can't be added.
+	Matched bool `json:"matched"`
+
+	// Seconds left before the conversation expires,
+	// it's computed with `RemainingSeconds`.
+	Remaining int `json:"remaining"`
+}
+
+// ExpiresAt returns the time the conversation expires, the
+// boolean is false if the conversation has no time limit.
+func (c Conversation) ExpiresAt() (time.Time, bool) {
+	if c.Duration == 0 {
+		return time.Time{}, false
+	}
+
+	return c.CreatedAt.Add(time.Duration(c.Duration) * time.Second), true
+}
+
+// IsExpired reports whether the time limit
+// of the conversation has elapsed by now.
+func (c Conversation) IsExpired(now time.Time) bool {
+	expiresAt, ok := c.ExpiresAt()
+	return ok && !now.Before(expiresAt)
+}
+
+// RemainingSeconds returns the seconds left before the conversation
+// expires, it's zero for ended conversations and for conversations
+// without a time limit.
+func (c Conversation) RemainingSeconds(now time.Time) int {
+	expiresAt, ok := c.ExpiresAt()
+	if !ok || c.EndedAt != nil {
+		return 0
+	}
+
+	remaining := expiresAt.Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(remaining.Seconds()))
+}
+
+// HasParticipant reports whether the user identified
+// by userId has joined the conversation.
+func (c Conversation) HasParticipant(userId int) bool {
+	for _, id := range c.Participants {
+		if id == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Message struct {
+	Id             int       `json:"id"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Content        string    `json:"content"`
+	UserId         int       `json:"userId"`
+	ConversationId int       `json:"conversationId"`
+}
+
+// HistoryQuery selects a page of the messages of a conversation,
+// at most one of the cursors Before and After can be set, when
+// none is set the page contains the most recent messages.
+type HistoryQuery struct {
+	// Cursor of the page with the messages older than it.
+	Before string
+
+	// Cursor of the page with the messages newer than it.
+	After string
+
+	// Maximum number of messages in the page.
+	Limit int
+}
+
+// MessagesPage is a page of the messages of a conversation,
+// the messages are sorted from the oldest to the newest.
+type MessagesPage struct {
+	Messages []Message `json:"messages"`
+
+	// Cursor for requesting the older messages,
+	// it's empty when there are no older messages.
+	Before string `json:"before,omitempty"`
+
+	// Cursor for requesting the newer messages, it's
+	// kept even when there are no newer messages yet so
+	// it can be used to poll for new messages.
+	After string `json:"after,omitempty"`
+}
+
 type Database struct {
-	UserManager UserManager
+	UserManager         UserManager
+	ConversationManager ConversationManager
+	MessageManager      MessageManager
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConversationRemainingSeconds(t *testing.T) {
+	createdAt := time.Date(2022, 11, 20, 10, 0, 0, 0, time.UTC)
+
+	conversation := Conversation{
+		CreatedAt: createdAt,
+		Duration:  60,
+	}
+
+	now := createdAt.Add(15 * time.Second)
+
+	if remaining := conversation.RemainingSeconds(now); remaining != 45 {
+		t.Errorf("expected remaining=%d, got remaining=%d", 45, remaining)
+	}
+
+	if conversation.IsExpired(now) {
+		t.Errorf("expected conversation not to be expired at %v", now)
+	}
+
+	now = createdAt.Add(60 * time.Second)
+
+	if remaining := conversation.RemainingSeconds(now); remaining != 0 {
+		t.Errorf("expected remaining=%d, got remaining=%d", 0, remaining)
+	}
+
+	if !conversation.IsExpired(now) {
+		t.Errorf("expected conversation to be expired at %v", now)
+	}
+
+	// Conversations without a time limit never expire.
+	conversation.Duration = 0
+
+	if conversation.IsExpired(now.Add(time.Hour)) {
+		t.Errorf("expected conversation without time limit not to expire")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
 	"golang.org/x/exp/slog"
 )
 
@@ -12,13 +13,28 @@ var (
 	rootCtx = context.Background()
 )
 
-func New(db *sql.DB, logger *slog.Logger) models.Database {
+// Creates the implementation of `models.Database` backed by db,
+// the passwords of the users are hashed with hasher.
+func New(db *sql.DB, hasher *hashing.Manager, logger *slog.Logger) models.Database {
 	userManager := &UserManager{
+		db:     db,
+		hasher: hasher,
+		logger: logger,
+	}
+
+	conversationManager := &ConversationManager{
+		db:     db,
+		logger: logger,
+	}
+
+	messageManager := &MessageManager{
 		db:     db,
 		logger: logger,
 	}
 
 	return models.Database{
-		UserManager: userManager,
+		UserManager:         userManager,
+		ConversationManager: conversationManager,
+		MessageManager:      messageManager,
 	}
 }
@@ -0,0 +1,31 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/Edwing123/udem-chat-app/pkg/migrations"
+)
+
+const createMigrationsTable = `
+IF OBJECT_ID(N'[Schema_Migration]', N'U') IS NULL
+CREATE TABLE [Schema_Migration] (
+    [Version] INT PRIMARY KEY,
+    [Name] NVARCHAR(100) NOT NULL,
+    [Applied_At] DATETIME NOT NULL
+);
+`
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Creates the migrator of the schema of the SQL Server database.
+func NewMigrator(db *sql.DB) (*migrations.Migrator, error) {
+	fsys, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.New(db, fsys, createMigrationsTable)
+}
@@ -1,6 +1,3 @@
-DROP DATABASE [Nameless]
-GO
-
 DROP TABLE IF EXISTS [User_Join_Conversation]
 GO
 
@@ -11,4 +8,4 @@ DROP TABLE IF EXISTS [User]
 GO
 
 DROP TABLE IF EXISTS [Conversation]
-GO
\ No newline at end of file
+GO
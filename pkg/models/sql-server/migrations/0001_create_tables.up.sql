@@ -1,6 +1,7 @@
-CREATE DATABASE [Nameless]
-GO
+-- The tables are only created if they don't exist, so databases
+-- created with the former hand-run schema can be migrated too.
 
+IF OBJECT_ID(N'[User]', N'U') IS NULL
 CREATE TABLE [User] (
     [Id] INT IDENTITY(1, 1) PRIMARY KEY,
 
@@ -24,6 +25,7 @@ CREATE TABLE [User] (
 )
 GO
 
+IF OBJECT_ID(N'[Conversation]', N'U') IS NULL
 CREATE TABLE [Conversation] (
     [Id] INT IDENTITY(1, 1) PRIMARY KEY,
 
@@ -37,6 +39,7 @@ CREATE TABLE [Conversation] (
 )
 GO
 
+IF OBJECT_ID(N'[Message]', N'U') IS NULL
 CREATE TABLE [Message] (
     [Id] INT IDENTITY(1, 1) PRIMARY KEY,
 
@@ -58,6 +61,7 @@ CREATE TABLE [Message] (
 )
 GO
 
+IF OBJECT_ID(N'[User_Join_Conversation]', N'U') IS NULL
 CREATE TABLE [User_Join_Conversation] (
     [Id] INT IDENTITY(1, 1) PRIMARY KEY,
 
@@ -69,3 +73,4 @@ CREATE TABLE [User_Join_Conversation] (
     CONSTRAINT [Foreign_User_Join_Conversation_User_Id] FOREIGN KEY (User_Id) REFERENCES [User](Id),
     CONSTRAINT [Foreign_User_Join_Conversation_Conversation_Id] FOREIGN KEY (Conversation_Id) REFERENCES [Conversation](Id)
 )
+GO
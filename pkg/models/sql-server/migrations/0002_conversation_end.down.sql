@@ -0,0 +1,5 @@
+ALTER TABLE [User_Join_Conversation] DROP CONSTRAINT IF EXISTS [Unique_User_Join_Conversation]
+GO
+
+ALTER TABLE [Conversation] DROP COLUMN IF EXISTS [Ended_At]
+GO
@@ -0,0 +1,11 @@
+-- The time the conversation was ended, NULL
+-- while the conversation is still active.
+IF COL_LENGTH(N'[Conversation]', N'Ended_At') IS NULL
+ALTER TABLE [Conversation] ADD [Ended_At] DATETIME NULL
+GO
+
+-- A user can join a conversation only once.
+IF OBJECT_ID(N'[Unique_User_Join_Conversation]', N'UQ') IS NULL
+ALTER TABLE [User_Join_Conversation]
+ADD CONSTRAINT [Unique_User_Join_Conversation] UNIQUE (User_Id, Conversation_Id)
+GO
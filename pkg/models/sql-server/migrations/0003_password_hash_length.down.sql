@@ -0,0 +1,5 @@
+-- It fails while a stored hash is longer than a bcrypt hash,
+-- those users must change their password (or be rehashed
+-- with bcrypt by logging in) before reverting.
+ALTER TABLE [User] ALTER COLUMN [Password] CHAR(60) NOT NULL
+GO
@@ -0,0 +1,4 @@
+-- The hashes produced by argon2id don't fit in the 60
+-- characters of a bcrypt hash, nor have a fixed length.
+ALTER TABLE [User] ALTER COLUMN [Password] VARCHAR(255) NOT NULL
+GO
@@ -0,0 +1,5 @@
+ALTER TABLE [Conversation] DROP CONSTRAINT IF EXISTS [Default_Conversation_Matched]
+GO
+
+ALTER TABLE [Conversation] DROP COLUMN IF EXISTS [Matched]
+GO
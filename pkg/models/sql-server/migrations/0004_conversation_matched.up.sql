@@ -0,0 +1,6 @@
+-- Whether the matchmaking queue created the conversation,
+-- the participants of those conversations can't be added.
+IF COL_LENGTH(N'[Conversation]', N'Matched') IS NULL
+ALTER TABLE [Conversation]
+ADD [Matched] BIT NOT NULL CONSTRAINT [Default_Conversation_Matched] DEFAULT 0
+GO
@@ -4,8 +4,22 @@ var (
 	userId               = "Id"
 	userName             = "Name"
 	userPassword         = "Password"
+	userOldPassword      = "Old_Password"
 	userBirthdate        = "Birthdate"
 	userProfilePictureId = "Profile_Picture_Id"
+
+	conversationId       = "Id"
+	conversationDuration = "Duration"
+	conversationMatched  = "Matched"
+
+	joinUserId         = "User_Id"
+	joinConversationId = "Conversation_Id"
+
+	messageId             = "Id"
+	messageContent        = "Content"
+	messageUserId         = "User_Id"
+	messageConversationId = "Conversation_Id"
+	messageLimit          = "Limit"
 )
 
 const (
@@ -43,4 +57,135 @@ const (
 	SET [Password] = @Password
 	WHERE Id = @Id;
 	`
+
+	rehashUserPassword = `
+	UPDATE [User]
+	SET [Password] = @Password
+	WHERE [Id] = @Id AND [Password] = @Old_Password;
+	`
+
+	getUserPasswordAndProfilePictureIdById = `
+	SELECT [Password], [Profile_Picture_Id]
+	FROM [User]
+	WHERE [Id] = @Id;
+	`
+
+	tombstoneUser = `
+	UPDATE [User]
+	SET [Name] = @Name, [Password] = @Password, [Birthdate] = @Birthdate, [Profile_Picture_Id] = NULL
+	WHERE [Id] = @Id;
+	`
+
+	deleteUser = `
+	DELETE FROM [User]
+	WHERE [Id] = @Id;
+	`
+
+	deleteMessagesByUserId = `
+	DELETE FROM [Message]
+	WHERE [User_Id] = @User_Id;
+	`
+
+	deleteParticipationsByUserId = `
+	DELETE FROM [User_Join_Conversation]
+	WHERE [User_Id] = @User_Id;
+	`
+
+	insertConversation = `
+	INSERT INTO [Conversation] ([Created_At], [Duration], [Matched])
+	OUTPUT INSERTED.[Id], INSERTED.[Created_At]
+	VALUES(GETUTCDATE(), @Duration, @Matched);
+	`
+
+	getConversationById = `
+	SELECT [Id], [Created_At], [Duration], [Ended_At], [Matched]
+	FROM [Conversation]
+	WHERE [Id] = @Id;
+	`
+
+	getConversationsByUserId = `
+	SELECT [C].[Id], [C].[Created_At], [C].[Duration], [C].[Ended_At], [C].[Matched]
+	FROM [Conversation] AS [C]
+	INNER JOIN [User_Join_Conversation] AS [J] ON [J].[Conversation_Id] = [C].[Id]
+	WHERE [J].[User_Id] = @User_Id
+	ORDER BY [C].[Created_At] DESC;
+	`
+
+	getExpiringConversations = `
+	SELECT [Id], [Created_At], [Duration], [Matched]
+	FROM [Conversation]
+	WHERE [Ended_At] IS NULL AND [Duration] > 0;
+	`
+
+	getParticipantsByConversationId = `
+	SELECT [User_Id]
+	FROM [User_Join_Conversation]
+	WHERE [Conversation_Id] = @Conversation_Id;
+	`
+
+	getParticipantsOfUserConversations = `
+	SELECT [Conversation_Id], [User_Id]
+	FROM [User_Join_Conversation]
+	WHERE [Conversation_Id] IN (
+		SELECT [Conversation_Id]
+		FROM [User_Join_Conversation]
+		WHERE [User_Id] = @User_Id
+	);
+	`
+
+	insertParticipant = `
+	INSERT INTO [User_Join_Conversation] ([User_Id], [Conversation_Id])
+	VALUES(@User_Id, @Conversation_Id);
+	`
+
+	deleteParticipant = `
+	DELETE FROM [User_Join_Conversation]
+	WHERE [User_Id] = @User_Id AND [Conversation_Id] = @Conversation_Id;
+	`
+
+	endConversation = `
+	UPDATE [Conversation]
+	SET [Ended_At] = GETUTCDATE()
+	WHERE [Id] = @Id;
+	`
+
+	// The message is only inserted if its author
+	// has joined the conversation.
+	insertMessage = `
+	INSERT INTO [Message] ([Created_At], [Content], [User_Id], [Conversation_Id])
+	OUTPUT INSERTED.[Id], INSERTED.[Created_At]
+	SELECT GETUTCDATE(), @Content, @User_Id, @Conversation_Id
+	WHERE EXISTS (
+		SELECT 1
+		FROM [User_Join_Conversation]
+		WHERE [User_Id] = @User_Id AND [Conversation_Id] = @Conversation_Id
+	);
+	`
+
+	getMessageById = `
+	SELECT [Id], [Created_At], [Content], [User_Id], [Conversation_Id]
+	FROM [Message]
+	WHERE [Id] = @Id;
+	`
+
+	getLatestMessages = `
+	SELECT TOP (@Limit) [Id], [Created_At], [Content], [User_Id], [Conversation_Id]
+	FROM [Message]
+	WHERE [Conversation_Id] = @Conversation_Id
+	ORDER BY [Id] DESC;
+	`
+
+	getMessagesBefore = `
+	SELECT TOP (@Limit) [Id], [Created_At], [Content], [User_Id], [Conversation_Id]
+	FROM [Message]
+	WHERE [Conversation_Id] = @Conversation_Id AND [Id] < @Id
+	ORDER BY [Id] DESC;
+	`
+
+	getMessagesAfter = `
+	SELECT TOP (@Limit) [Id], [Created_At], [Content], [User_Id], [Conversation_Id]
+	FROM [Message]
+	WHERE [Conversation_Id] = @Conversation_Id AND [Id] > @Id
+	ORDER BY [Id] ASC;
+	`
 )
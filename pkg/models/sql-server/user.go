@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/Edwing123/udem-chat-app/pkg/models"
 	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
@@ -15,6 +14,7 @@ import (
 
 type UserManager struct {
 	db     *sql.DB
+	hasher *hashing.Manager
 	logger *slog.Logger
 }
 
@@ -24,38 +24,15 @@ func isUserNameExistsError(err error) bool {
 	return strings.Contains(sqlErr.Message, "Unique_User_Name")
 }
 
-func isValidBirthdateFormat(birthdate string) bool {
-	_, err := time.Parse(models.UserBirthdateFormat, birthdate)
-	return err == nil
-}
-
 func (um *UserManager) New(user models.User) error {
-	var err error
-
 	// Validate user input.
-	switch true {
-	case user.Name == "":
-		err = models.ErrUserNameEmpty
-
-	case len(user.Name) > models.UserNameMaxLength:
-		err = models.ErrUserNameExceedsMaxLength
-
-	case user.Password == "":
-		err = models.ErrUserPasswordEmpty
-
-	case user.Birthdate == "":
-		err = models.ErrUserBirthdateEmpty
-
-	case !isValidBirthdateFormat(user.Birthdate):
-		err = models.ErrUserBirthdateBadFormat
-	}
-
+	err := models.ValidateNewUser(user)
 	if err != nil {
 		return err
 	}
 
 	// Hash the password.
-	hashedPassword, err := hashing.HashPassword([]byte(user.Password))
+	hashedPassword, err := um.hasher.HashPassword([]byte(user.Password))
 	if err != nil {
 		um.logger.Error("Hash password", err)
 		return hashing.ErrPasswordHashingFail
@@ -115,18 +92,10 @@ func (um *UserManager) Get(id int) (models.User, error) {
 }
 
 func (um *UserManager) Login(user models.User) (int, error) {
-	var err error
-
 	// Validate user input.
-	switch true {
-	case user.Name == "":
-		err = models.ErrUserNameEmpty
-
-	case len(user.Name) > models.UserNameMaxLength:
-		err = models.ErrUserNameExceedsMaxLength
-
-	case user.Password == "":
-		err = models.ErrUserPasswordEmpty
+	err := models.ValidateLogin(user)
+	if err != nil {
+		return 0, err
 	}
 
 	var userId int
@@ -150,42 +119,63 @@ func (um *UserManager) Login(user models.User) (int, error) {
 		return 0, models.ErrDatabaseServerFail
 	}
 
-	isPasswordValid := hashing.VerifyPassword([]byte(hashedPassword), []byte(user.Password))
+	isPasswordValid, outdated := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(user.Password))
 	if !isPasswordValid {
 		return 0, models.ErrLoginFail
 	}
 
+	if outdated {
+		um.rehash(userId, hashedPassword, user.Password)
+	}
+
 	return userId, nil
 }
 
+// Replaces the outdated hash of the password of the user identified
+// by id, failures are only logged since the outdated hash still works.
+//
+// The hash is only replaced if it didn't change in the meantime,
+// otherwise a concurrent password change could be undone.
+func (um *UserManager) rehash(id int, hashedPassword string, password string) {
+	newHashedPass, err := um.hasher.HashPassword([]byte(password))
+	if err != nil {
+		um.logger.Error("Rehash user password", err, "userId", id)
+		return
+	}
+
+	_, err = um.db.ExecContext(
+		rootCtx,
+		rehashUserPassword,
+		sql.Named(userPassword, string(newHashedPass)),
+		sql.Named(userOldPassword, hashedPassword),
+		sql.Named(userId, id),
+	)
+	if err != nil {
+		um.logger.Error("Rehash user password", err, "userId", id)
+	}
+}
+
 func (um *UserManager) Update(id int, user models.User) (models.User, string, error) {
+	err := models.ValidateUserUpdate(user)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
 	// Only update non-empty fields.
 	fieldsToUpdate := []string{}
 	values := []any{}
 
 	if user.Name != "" {
-		if len(user.Name) > models.UserNameMaxLength {
-			return models.User{}, "", models.ErrUserNameExceedsMaxLength
-		}
-
 		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userName, userName))
 		values = append(values, sql.Named(userName, user.Name))
 	}
 
 	if user.Birthdate != "" {
-		if !isValidBirthdateFormat(user.Birthdate) {
-			return models.User{}, "", models.ErrUserBirthdateBadFormat
-		}
-
 		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userBirthdate, userBirthdate))
 		values = append(values, sql.Named(userBirthdate, user.Birthdate))
 	}
 
 	if user.ProfilePictureId != "" {
-		if len(user.ProfilePictureId) > models.UserProfilePictureIdLength {
-			return models.User{}, "", models.ErrUserProfilePictureIdNotValidLength
-		}
-
 		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userProfilePictureId, userProfilePictureId))
 		values = append(values, sql.Named(userProfilePictureId, user.ProfilePictureId))
 	}
@@ -288,12 +278,12 @@ func (um *UserManager) ChangePassword(id int, currentPass, newPass string) error
 		return models.ErrDatabaseServerFail
 	}
 
-	isValidPassword := hashing.VerifyPassword([]byte(hashedPassword), []byte(currentPass))
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(currentPass))
 	if !isValidPassword {
 		return models.ErrPasswordMismatch
 	}
 
-	newHashedPass, err := hashing.HashPassword([]byte(newPass))
+	newHashedPass, err := um.hasher.HashPassword([]byte(newPass))
 	if err != nil {
 		return hashing.ErrPasswordHashingFail
 	}
@@ -310,3 +300,87 @@ func (um *UserManager) ChangePassword(id int, currentPass, newPass string) error
 
 	return nil
 }
+
+// Deletes the account of the user identified by id once its password
+// is confirmed, the data of the user is handled according to policy.
+//
+// It returns the id of the profile picture of the user (if any),
+// so the caller can archive it.
+func (um *UserManager) Delete(id int, password string, policy models.DeletionPolicy) (string, error) {
+	if password == "" {
+		return "", models.ErrUserPasswordEmpty
+	}
+
+	if !policy.IsValid() {
+		return "", models.ErrUserDeletionPolicyNotValid
+	}
+
+	tx, err := um.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		um.logger.Error("Delete user - begin transaction", err)
+		return "", models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	var hashedPassword string
+	var nullableImageId sql.NullString
+
+	row := tx.QueryRowContext(
+		rootCtx,
+		getUserPasswordAndProfilePictureIdById,
+		sql.Named(userId, id),
+	)
+
+	err = row.Scan(&hashedPassword, &nullableImageId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrNoRecords
+		}
+
+		um.logger.Error("Delete user", err, "userId", id)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(password))
+	if !isValidPassword {
+		return "", models.ErrPasswordMismatch
+	}
+
+	switch policy {
+	case models.DeletionPolicyDelete:
+		// The rows referencing the user go first.
+		for _, query := range []string{deleteMessagesByUserId, deleteParticipationsByUserId} {
+			_, err = tx.ExecContext(rootCtx, query, sql.Named(joinUserId, id))
+			if err != nil {
+				um.logger.Error("Delete user - delete references", err, "userId", id)
+				return "", models.ErrDatabaseServerFail
+			}
+		}
+
+		_, err = tx.ExecContext(rootCtx, deleteUser, sql.Named(userId, id))
+
+	case models.DeletionPolicyTombstone:
+		tombstone := models.TombstoneUser(id)
+
+		_, err = tx.ExecContext(
+			rootCtx,
+			tombstoneUser,
+			sql.Named(userName, tombstone.Name),
+			sql.Named(userPassword, tombstone.Password),
+			sql.Named(userBirthdate, tombstone.Birthdate),
+			sql.Named(userId, id),
+		)
+	}
+	if err != nil {
+		um.logger.Error("Delete user", err, "userId", id, "policy", policy)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		um.logger.Error("Delete user - close transaction", err)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	return nullableImageId.String, nil
+}
@@ -0,0 +1,413 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"golang.org/x/exp/slog"
+)
+
+// Common interface of *sql.DB and *sql.Tx, it allows
+// helpers to run either inside or outside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type ConversationManager struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+// Reads the conversation identified by id along with its participants.
+func (cm *ConversationManager) get(q querier, id int) (models.Conversation, error) {
+	row := q.QueryRowContext(
+		rootCtx,
+		getConversationById,
+		sql.Named(conversationId, id),
+	)
+
+	var conversation models.Conversation
+	var nullableEndedAt sql.NullTime
+
+	err := row.Scan(
+		&conversation.Id,
+		&conversation.CreatedAt,
+		&conversation.Duration,
+		&nullableEndedAt,
+		&conversation.Matched,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return conversation, models.ErrNoRecords
+		}
+
+		cm.logger.Error("Get conversation", err, "conversationId", id)
+		return conversation, models.ErrDatabaseServerFail
+	}
+
+	if nullableEndedAt.Valid {
+		conversation.EndedAt = &nullableEndedAt.Time
+	}
+
+	conversation.Remaining = conversation.RemainingSeconds(time.Now())
+
+	rows, err := q.QueryContext(
+		rootCtx,
+		getParticipantsByConversationId,
+		sql.Named(joinConversationId, id),
+	)
+	if err != nil {
+		cm.logger.Error("Get conversation participants", err, "conversationId", id)
+		return conversation, models.ErrDatabaseServerFail
+	}
+	defer rows.Close()
+
+	conversation.Participants = []int{}
+
+	for rows.Next() {
+		var userId int
+
+		err := rows.Scan(&userId)
+		if err != nil {
+			cm.logger.Error("Get conversation participants - scan", err, "conversationId", id)
+			return conversation, models.ErrDatabaseServerFail
+		}
+
+		conversation.Participants = append(conversation.Participants, userId)
+	}
+
+	if err := rows.Err(); err != nil {
+		cm.logger.Error("Get conversation participants - iterate", err, "conversationId", id)
+		return conversation, models.ErrDatabaseServerFail
+	}
+
+	return conversation, nil
+}
+
+// Makes the user identified by userId join the conversation.
+func (cm *ConversationManager) join(q querier, id int, userId int) error {
+	_, err := q.ExecContext(
+		rootCtx,
+		insertParticipant,
+		sql.Named(joinUserId, userId),
+		sql.Named(joinConversationId, id),
+	)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return models.ErrParticipantExists
+		}
+
+		if isForeignKeyConstraintError(err) {
+			return models.ErrNoRecords
+		}
+
+		cm.logger.Error("Join conversation", err, "conversationId", id, "userId", userId)
+		return models.ErrDatabaseServerFail
+	}
+
+	return nil
+}
+
+// Creates a conversation lasting duration seconds, the users
+// identified by participants join the conversation on creation,
+// matched marks the conversations created by the matchmaking queue.
+func (cm *ConversationManager) New(duration int, participants []int, matched bool) (models.Conversation, error) {
+	err := models.ValidateConversationDuration(duration)
+	if err != nil {
+		return models.Conversation{}, err
+	}
+
+	tx, err := cm.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		cm.logger.Error("New conversation - begin transaction", err)
+		return models.Conversation{}, models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	conversation := models.Conversation{
+		CreatedAt:    time.Now().UTC(),
+		Duration:     duration,
+		Participants: []int{},
+		Matched:      matched,
+	}
+
+	row := tx.QueryRowContext(
+		rootCtx,
+		insertConversation,
+		sql.Named(conversationCreatedAt, conversation.CreatedAt),
+		sql.Named(conversationDuration, duration),
+		sql.Named(conversationMatched, matched),
+	)
+
+	err = row.Scan(&conversation.Id)
+	if err != nil {
+		cm.logger.Error("New conversation", err, "duration", duration)
+		return models.Conversation{}, models.ErrDatabaseServerFail
+	}
+
+	for _, userId := range participants {
+		// Ignore repeated participants.
+		if conversation.HasParticipant(userId) {
+			continue
+		}
+
+		err := cm.join(tx, conversation.Id, userId)
+		if err != nil {
+			return models.Conversation{}, err
+		}
+
+		conversation.Participants = append(conversation.Participants, userId)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		cm.logger.Error("New conversation - close transaction", err)
+		return models.Conversation{}, models.ErrDatabaseServerFail
+	}
+
+	conversation.Remaining = conversation.RemainingSeconds(time.Now())
+
+	return conversation, nil
+}
+
+func (cm *ConversationManager) Get(id int) (models.Conversation, error) {
+	return cm.get(cm.db, id)
+}
+
+// Returns the conversations the user identified by userId
+// has joined, the most recent ones come first.
+func (cm *ConversationManager) ListForUser(userId int) ([]models.Conversation, error) {
+	rows, err := cm.db.QueryContext(
+		rootCtx,
+		getConversationsByUserId,
+		sql.Named(joinUserId, userId),
+	)
+	if err != nil {
+		cm.logger.Error("List conversations", err, "userId", userId)
+		return nil, models.ErrDatabaseServerFail
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+
+	// Position of each conversation inside the slice, used
+	// to attach the participants to their conversation.
+	indexes := map[int]int{}
+
+	for rows.Next() {
+		var conversation models.Conversation
+		var nullableEndedAt sql.NullTime
+
+		err := rows.Scan(
+			&conversation.Id,
+			&conversation.CreatedAt,
+			&conversation.Duration,
+			&nullableEndedAt,
+			&conversation.Matched,
+		)
+		if err != nil {
+			cm.logger.Error("List conversations - scan", err, "userId", userId)
+			return nil, models.ErrDatabaseServerFail
+		}
+
+		if nullableEndedAt.Valid {
+			conversation.EndedAt = &nullableEndedAt.Time
+		}
+
+		conversation.Participants = []int{}
+		conversation.Remaining = conversation.RemainingSeconds(time.Now())
+
+		indexes[conversation.Id] = len(conversations)
+		conversations = append(conversations, conversation)
+	}
+
+	if err := rows.Err(); err != nil {
+		cm.logger.Error("List conversations - iterate", err, "userId", userId)
+		return nil, models.ErrDatabaseServerFail
+	}
+
+	participantsRows, err := cm.db.QueryContext(
+		rootCtx,
+		getParticipantsOfUserConversations,
+		sql.Named(joinUserId, userId),
+	)
+	if err != nil {
+		cm.logger.Error("List conversations participants", err, "userId", userId)
+		return nil, models.ErrDatabaseServerFail
+	}
+	defer participantsRows.Close()
+
+	for participantsRows.Next() {
+		var id, participantId int
+
+		err := participantsRows.Scan(&id, &participantId)
+		if err != nil {
+			cm.logger.Error("List conversations participants - scan", err, "userId", userId)
+			return nil, models.ErrDatabaseServerFail
+		}
+
+		index, ok := indexes[id]
+		if !ok {
+			continue
+		}
+
+		conversations[index].Participants = append(conversations[index].Participants, participantId)
+	}
+
+	if err := participantsRows.Err(); err != nil {
+		cm.logger.Error("List conversations participants - iterate", err, "userId", userId)
+		return nil, models.ErrDatabaseServerFail
+	}
+
+	return conversations, nil
+}
+
+// Returns the active conversations that have a time limit,
+// the participants of the conversations are not included.
+func (cm *ConversationManager) ListExpiring() ([]models.Conversation, error) {
+	rows, err := cm.db.QueryContext(rootCtx, getExpiringConversations)
+	if err != nil {
+		cm.logger.Error("List expiring conversations", err)
+		return nil, models.ErrDatabaseServerFail
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+
+	for rows.Next() {
+		var conversation models.Conversation
+
+		err := rows.Scan(
+			&conversation.Id,
+			&conversation.CreatedAt,
+			&conversation.Duration,
+			&conversation.Matched,
+		)
+		if err != nil {
+			cm.logger.Error("List expiring conversations - scan", err)
+			return nil, models.ErrDatabaseServerFail
+		}
+
+		conversation.Remaining = conversation.RemainingSeconds(time.Now())
+		conversations = append(conversations, conversation)
+	}
+
+	if err := rows.Err(); err != nil {
+		cm.logger.Error("List expiring conversations - iterate", err)
+		return nil, models.ErrDatabaseServerFail
+	}
+
+	return conversations, nil
+}
+
+func (cm *ConversationManager) AddParticipant(id int, userId int) error {
+	tx, err := cm.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		cm.logger.Error("Add participant - begin transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	conversation, err := cm.get(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if conversation.EndedAt != nil {
+		return models.ErrConversationEnded
+	}
+
+	err = cm.join(tx, id, userId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		cm.logger.Error("Add participant - close transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+
+	return nil
+}
+
+func (cm *ConversationManager) RemoveParticipant(id int, userId int) error {
+	tx, err := cm.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		cm.logger.Error("Remove participant - begin transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	conversation, err := cm.get(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if !conversation.HasParticipant(userId) {
+		return models.ErrNotParticipant
+	}
+
+	_, err = tx.ExecContext(
+		rootCtx,
+		deleteParticipant,
+		sql.Named(joinUserId, userId),
+		sql.Named(joinConversationId, id),
+	)
+	if err != nil {
+		cm.logger.Error("Remove participant", err, "conversationId", id, "userId", userId)
+		return models.ErrDatabaseServerFail
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		cm.logger.Error("Remove participant - close transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+
+	return nil
+}
+
+// Marks the conversation as ended, an ended
+// conversation can not be ended again.
+func (cm *ConversationManager) End(id int) error {
+	tx, err := cm.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		cm.logger.Error("End conversation - begin transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	conversation, err := cm.get(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if conversation.EndedAt != nil {
+		return models.ErrConversationEnded
+	}
+
+	_, err = tx.ExecContext(
+		rootCtx,
+		endConversation,
+		sql.Named(conversationEndedAt, time.Now().UTC()),
+		sql.Named(conversationId, id),
+	)
+	if err != nil {
+		cm.logger.Error("End conversation", err, "conversationId", id)
+		return models.ErrDatabaseServerFail
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		cm.logger.Error("End conversation - close transaction", err)
+		return models.ErrDatabaseServerFail
+	}
+
+	return nil
+}
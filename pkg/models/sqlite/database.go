@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	rootCtx = context.Background()
+)
+
+// Opens the SQLite database stored in the file at path, the file
+// is created if it doesn't exist, the tables are created by the
+// migrations (see `NewMigrator`).
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer at a time.
+	db.SetMaxOpenConns(1)
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Creates the implementation of `models.Database` backed by db,
+// the passwords of the users are hashed with hasher.
+func New(db *sql.DB, hasher *hashing.Manager, logger *slog.Logger) models.Database {
+	userManager := &UserManager{
+		db:     db,
+		hasher: hasher,
+		logger: logger,
+	}
+
+	conversationManager := &ConversationManager{
+		db:     db,
+		logger: logger,
+	}
+
+	messageManager := &MessageManager{
+		db:     db,
+		logger: logger,
+	}
+
+	return models.Database{
+		UserManager:         userManager,
+		ConversationManager: conversationManager,
+		MessageManager:      messageManager,
+	}
+}
+
+func isUniqueConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	_ = errors.As(err, &sqliteErr)
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func isForeignKeyConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	_ = errors.As(err, &sqliteErr)
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+}
@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"golang.org/x/exp/slog"
+)
+
+func newTestDatabase(t *testing.T) models.Database {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "nameless.db"))
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	return New(db, hashing.Default(), slog.New(slog.NewTextHandler(io.Discard)))
+}
+
+func TestDatabase(t *testing.T) {
+	database := newTestDatabase(t)
+
+	for _, name := range []string{"foo", "bar"} {
+		err := database.UserManager.New(models.User{
+			Name:      name,
+			Password:  "password#123",
+			Birthdate: "2000-01-01",
+		})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	err := database.UserManager.New(models.User{Name: "FOO", Password: "foo", Birthdate: "2000-01-01"})
+	if err != models.ErrUserNameExists {
+		t.Errorf("expected %v, got %v", models.ErrUserNameExists, err)
+	}
+
+	fooId, err := database.UserManager.Login(models.User{Name: "foo", Password: "password#123"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	foo, err := database.UserManager.Get(fooId)
+	if err != nil || foo.Birthdate != "2000-01-01" {
+		t.Errorf("expected birthdate=%q and nil error, got birthdate=%q and %v", "2000-01-01", foo.Birthdate, err)
+	}
+
+	conversation, err := database.ConversationManager.New(60, []int{fooId, fooId + 1}, true)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	stored, err := database.ConversationManager.Get(conversation.Id)
+	if err != nil || !stored.Matched {
+		t.Errorf("expected matched=true and nil error, got matched=%v and %v", stored.Matched, err)
+	}
+
+	err = database.ConversationManager.AddParticipant(conversation.Id, fooId)
+	if err != models.ErrParticipantExists {
+		t.Errorf("expected %v, got %v", models.ErrParticipantExists, err)
+	}
+
+	err = database.ConversationManager.AddParticipant(conversation.Id, 1000)
+	if err != models.ErrNoRecords {
+		t.Errorf("expected %v, got %v", models.ErrNoRecords, err)
+	}
+
+	for _, content := range []string{"hola", "que tal", "bien"} {
+		_, err := database.MessageManager.New(models.Message{
+			Content:        content,
+			UserId:         fooId,
+			ConversationId: conversation.Id,
+		})
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	_, err = database.MessageManager.New(models.Message{
+		Content:        "hola",
+		UserId:         1000,
+		ConversationId: conversation.Id,
+	})
+	if err != models.ErrNotParticipant {
+		t.Errorf("expected %v, got %v", models.ErrNotParticipant, err)
+	}
+
+	page, err := database.MessageManager.History(conversation.Id, models.HistoryQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(page.Messages) != 2 || page.Messages[1].Content != "bien" || page.Before == "" {
+		t.Errorf("expected the 2 newest messages and a before cursor, got %+v", page)
+	}
+
+	err = database.ConversationManager.End(conversation.Id)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	conversations, err := database.ConversationManager.ListForUser(fooId)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(conversations) != 1 || conversations[0].EndedAt == nil || len(conversations[0].Participants) != 2 {
+		t.Errorf("expected one ended conversation with 2 participants, got %+v", conversations)
+	}
+
+	// The tombstone keeps the messages of the user.
+	_, err = database.UserManager.Delete(fooId+1, "wrong", models.DeletionPolicyTombstone)
+	if err != models.ErrPasswordMismatch {
+		t.Errorf("expected %v, got %v", models.ErrPasswordMismatch, err)
+	}
+
+	_, err = database.UserManager.Delete(fooId+1, "password#123", models.DeletionPolicyTombstone)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	bar, err := database.UserManager.Get(fooId + 1)
+	if err != nil || bar.Name != models.TombstoneUser(fooId+1).Name {
+		t.Errorf("expected name=%q and nil error, got name=%q and %v", models.TombstoneUser(fooId+1).Name, bar.Name, err)
+	}
+
+	// The deletion removes the messages of the user.
+	_, err = database.UserManager.Delete(fooId, "password#123", models.DeletionPolicyDelete)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = database.UserManager.Get(fooId)
+	if err != models.ErrNoRecords {
+		t.Errorf("expected %v, got %v", models.ErrNoRecords, err)
+	}
+
+	page, err = database.MessageManager.History(conversation.Id, models.HistoryQuery{})
+	if err != nil || len(page.Messages) != 0 {
+		t.Errorf("expected no messages and nil error, got %d messages and %v", len(page.Messages), err)
+	}
+}
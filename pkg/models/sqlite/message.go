@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"golang.org/x/exp/slog"
+)
+
+type MessageManager struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+// Stores the message, its author must have joined the conversation.
+func (mm *MessageManager) New(message models.Message) (models.Message, error) {
+	err := models.ValidateMessageContent(message.Content)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	message.CreatedAt = time.Now().UTC()
+
+	row := mm.db.QueryRowContext(
+		rootCtx,
+		insertMessage,
+		sql.Named(messageCreatedAt, message.CreatedAt),
+		sql.Named(messageContent, message.Content),
+		sql.Named(messageUserId, message.UserId),
+		sql.Named(messageConversationId, message.ConversationId),
+	)
+
+	err = row.Scan(&message.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Message{}, models.ErrNotParticipant
+		}
+
+		mm.logger.Error(
+			"New message", err,
+			"userId", message.UserId,
+			"conversationId", message.ConversationId,
+		)
+		return models.Message{}, models.ErrDatabaseServerFail
+	}
+
+	return message, nil
+}
+
+func (mm *MessageManager) Get(id int) (models.Message, error) {
+	row := mm.db.QueryRowContext(
+		rootCtx,
+		getMessageById,
+		sql.Named(messageId, id),
+	)
+
+	var message models.Message
+
+	err := row.Scan(
+		&message.Id,
+		&message.CreatedAt,
+		&message.Content,
+		&message.UserId,
+		&message.ConversationId,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return message, models.ErrNoRecords
+		}
+
+		mm.logger.Error("Get message", err, "messageId", id)
+		return message, models.ErrDatabaseServerFail
+	}
+
+	return message, nil
+}
+
+// Returns a page of the messages of the conversation
+// selected by the cursors of the query.
+func (mm *MessageManager) History(conversationId int, query models.HistoryQuery) (models.MessagesPage, error) {
+	if query.Before != "" && query.After != "" {
+		return models.MessagesPage{}, models.ErrMessageHistoryCursorsConflicting
+	}
+
+	// Fetch one more message than the limit to
+	// know whether there are more messages.
+	values := []any{
+		sql.Named(messageLimit, query.PageLimit()+1),
+		sql.Named(messageConversationId, conversationId),
+	}
+
+	statement := getLatestMessages
+
+	if query.Before != "" || query.After != "" {
+		cursor := query.Before
+		statement = getMessagesBefore
+
+		if query.After != "" {
+			cursor = query.After
+			statement = getMessagesAfter
+		}
+
+		id, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return models.MessagesPage{}, err
+		}
+
+		values = append(values, sql.Named(messageId, id))
+	}
+
+	rows, err := mm.db.QueryContext(rootCtx, statement, values...)
+	if err != nil {
+		mm.logger.Error("Messages history", err, "conversationId", conversationId)
+		return models.MessagesPage{}, models.ErrDatabaseServerFail
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+
+	for rows.Next() {
+		var message models.Message
+
+		err := rows.Scan(
+			&message.Id,
+			&message.CreatedAt,
+			&message.Content,
+			&message.UserId,
+			&message.ConversationId,
+		)
+		if err != nil {
+			mm.logger.Error("Messages history - scan", err, "conversationId", conversationId)
+			return models.MessagesPage{}, models.ErrDatabaseServerFail
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		mm.logger.Error("Messages history - iterate", err, "conversationId", conversationId)
+		return models.MessagesPage{}, models.ErrDatabaseServerFail
+	}
+
+	return models.NewMessagesPage(query, messages), nil
+}
@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/Edwing123/udem-chat-app/pkg/migrations"
+)
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS [Schema_Migration] (
+    [Version] INTEGER PRIMARY KEY,
+    [Name] TEXT NOT NULL,
+    [Applied_At] DATETIME NOT NULL
+);
+`
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Creates the migrator of the schema of the SQLite database.
+func NewMigrator(db *sql.DB) (*migrations.Migrator, error) {
+	fsys, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations.New(db, fsys, createMigrationsTable)
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS [User_Join_Conversation];
+
+DROP TABLE IF EXISTS [Message];
+
+DROP TABLE IF EXISTS [User];
+
+DROP TABLE IF EXISTS [Conversation];
@@ -0,0 +1,77 @@
+-- Equivalent of the SQL Server schema (sql-server/migrations).
+
+CREATE TABLE [User] (
+    [Id] INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    -- Names are compared without case, like the
+    -- default collation of SQL Server does.
+    [Name] TEXT NOT NULL COLLATE NOCASE,
+
+    -- The plain text password is hashed using Bcrypt.
+    [Password] TEXT NOT NULL,
+
+    -- Stored as text with the format YYYY-MM-DD.
+    [Birthdate] TEXT NOT NULL,
+
+    [Profile_Picture_Id] TEXT NULL,
+
+    -- Username must not be empty.
+    CONSTRAINT [Check_User_Name_Not_Empty] CHECK (LENGTH(Name) > 0),
+
+    -- Username must be unique.
+    CONSTRAINT [Unique_User_Name] UNIQUE (Name)
+);
+
+CREATE TABLE [Conversation] (
+    [Id] INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    [Created_At] DATETIME NOT NULL,
+
+    -- The duration of the conversation in seconds.
+    [Duration] INTEGER NOT NULL,
+
+    -- The time the conversation was ended, NULL
+    -- while the conversation is still active.
+    [Ended_At] DATETIME NULL,
+
+    -- The duration of the conversation must not be negative.
+    CONSTRAINT [Check_Conversation_Duration_Not_Negative] CHECK (Duration >= 0)
+);
+
+CREATE TABLE [Message] (
+    [Id] INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    [Created_At] DATETIME NOT NULL,
+
+    -- 300 is the maximum of characters per message.
+    [Content] TEXT NOT NULL,
+
+    [User_Id] INTEGER NOT NULL,
+
+    [Conversation_Id] INTEGER NOT NULL,
+
+    -- Foreign key references.
+    CONSTRAINT [Foreign_Message_User_Id] FOREIGN KEY (User_Id) REFERENCES [User](Id),
+    CONSTRAINT [Foreign_Message_Conversation_Id] FOREIGN KEY (Conversation_Id) REFERENCES [Conversation](Id),
+
+    -- The message must not be empty.
+    CONSTRAINT [Check_Message_Content_Not_Empty] CHECK (LENGTH(TRIM(Content)) > 0),
+    CONSTRAINT [Check_Message_Content_Max_Length] CHECK (LENGTH(Content) <= 300)
+);
+
+CREATE INDEX [Index_Message_Conversation_Id] ON [Message] (Conversation_Id, Id);
+
+CREATE TABLE [User_Join_Conversation] (
+    [Id] INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    [User_Id] INTEGER NOT NULL,
+
+    [Conversation_Id] INTEGER NOT NULL,
+
+    -- Foreign key references.
+    CONSTRAINT [Foreign_User_Join_Conversation_User_Id] FOREIGN KEY (User_Id) REFERENCES [User](Id),
+    CONSTRAINT [Foreign_User_Join_Conversation_Conversation_Id] FOREIGN KEY (Conversation_Id) REFERENCES [Conversation](Id),
+
+    -- A user can join a conversation only once.
+    CONSTRAINT [Unique_User_Join_Conversation] UNIQUE (User_Id, Conversation_Id)
+);
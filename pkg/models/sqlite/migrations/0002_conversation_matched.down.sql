@@ -0,0 +1 @@
+ALTER TABLE [Conversation] DROP COLUMN [Matched];
@@ -0,0 +1,3 @@
+-- Whether the matchmaking queue created the conversation,
+-- the participants of those conversations can't be added.
+ALTER TABLE [Conversation] ADD COLUMN [Matched] INTEGER NOT NULL DEFAULT 0;
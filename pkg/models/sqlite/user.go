@@ -0,0 +1,379 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Edwing123/udem-chat-app/pkg/models"
+	"github.com/Edwing123/udem-chat-app/pkg/validations/hashing"
+	"golang.org/x/exp/slog"
+)
+
+type UserManager struct {
+	db     *sql.DB
+	hasher *hashing.Manager
+	logger *slog.Logger
+}
+
+func (um *UserManager) New(user models.User) error {
+	// Validate user input.
+	err := models.ValidateNewUser(user)
+	if err != nil {
+		return err
+	}
+
+	// Hash the password.
+	hashedPassword, err := um.hasher.HashPassword([]byte(user.Password))
+	if err != nil {
+		um.logger.Error("Hash password", err)
+		return hashing.ErrPasswordHashingFail
+	}
+
+	_, err = um.db.ExecContext(
+		rootCtx,
+		insertUser,
+		sql.Named(userName, user.Name),
+		sql.Named(userPassword, string(hashedPassword)),
+		sql.Named(userBirthdate, user.Birthdate),
+	)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			err = models.ErrUserNameExists
+		} else {
+			um.logger.Error("New user", err, "name", user.Name, "birthdate", user.Birthdate)
+			err = models.ErrDatabaseServerFail
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (um *UserManager) Get(id int) (models.User, error) {
+	row := um.db.QueryRowContext(
+		rootCtx,
+		getUserById,
+		sql.Named(userId, id),
+	)
+
+	var user models.User
+	var nullableImageId sql.NullString
+
+	err := row.Scan(
+		&user.Id,
+		&user.Name,
+		&user.Birthdate,
+		&nullableImageId,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, models.ErrNoRecords
+		}
+
+		um.logger.Error("Get user", err, "userId", id)
+		return user, models.ErrDatabaseServerFail
+	}
+
+	if nullableImageId.Valid {
+		user.ProfilePictureId = nullableImageId.String
+	}
+
+	return user, nil
+}
+
+func (um *UserManager) Login(user models.User) (int, error) {
+	// Validate user input.
+	err := models.ValidateLogin(user)
+	if err != nil {
+		return 0, err
+	}
+
+	var userId int
+	var hashedPassword string
+
+	row := um.db.QueryRowContext(
+		rootCtx,
+		getUserIdAndPasswordByName,
+		sql.Named(userName, user.Name),
+	)
+	err = row.Scan(
+		&userId,
+		&hashedPassword,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, models.ErrLoginFail
+		}
+
+		um.logger.Error("Login user", err, "name", user.Name)
+		return 0, models.ErrDatabaseServerFail
+	}
+
+	isPasswordValid, outdated := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(user.Password))
+	if !isPasswordValid {
+		return 0, models.ErrLoginFail
+	}
+
+	if outdated {
+		um.rehash(userId, hashedPassword, user.Password)
+	}
+
+	return userId, nil
+}
+
+// Replaces the outdated hash of the password of the user identified
+// by id, failures are only logged since the outdated hash still works.
+//
+// The hash is only replaced if it didn't change in the meantime,
+// otherwise a concurrent password change could be undone.
+func (um *UserManager) rehash(id int, hashedPassword string, password string) {
+	newHashedPass, err := um.hasher.HashPassword([]byte(password))
+	if err != nil {
+		um.logger.Error("Rehash user password", err, "userId", id)
+		return
+	}
+
+	_, err = um.db.ExecContext(
+		rootCtx,
+		rehashUserPassword,
+		sql.Named(userPassword, string(newHashedPass)),
+		sql.Named(userOldPassword, hashedPassword),
+		sql.Named(userId, id),
+	)
+	if err != nil {
+		um.logger.Error("Rehash user password", err, "userId", id)
+	}
+}
+
+func (um *UserManager) Update(id int, user models.User) (models.User, string, error) {
+	err := models.ValidateUserUpdate(user)
+	if err != nil {
+		return models.User{}, "", err
+	}
+
+	// Only update non-empty fields.
+	fieldsToUpdate := []string{}
+	values := []any{}
+
+	if user.Name != "" {
+		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userName, userName))
+		values = append(values, sql.Named(userName, user.Name))
+	}
+
+	if user.Birthdate != "" {
+		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userBirthdate, userBirthdate))
+		values = append(values, sql.Named(userBirthdate, user.Birthdate))
+	}
+
+	if user.ProfilePictureId != "" {
+		fieldsToUpdate = append(fieldsToUpdate, fmt.Sprintf("%s = @%s", userProfilePictureId, userProfilePictureId))
+		values = append(values, sql.Named(userProfilePictureId, user.ProfilePictureId))
+	}
+
+	// If all update-able fields are empty, then return an error to notify
+	// that no updates were performed.
+	if len(fieldsToUpdate) == 0 {
+		return models.User{}, "", models.ErrNoUpdates
+	}
+
+	// Otherwise, build the query with the columns
+	// that will be updated.
+	query := fmt.Sprintf(
+		`UPDATE [User] SET %s WHERE [Id] = @Id;`,
+		strings.Join(fieldsToUpdate, ","),
+	)
+
+	values = append(values, sql.Named(userId, id))
+
+	tx, err := um.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		um.logger.Error("Update user - begin transaction", err, "details")
+		return models.User{}, "", models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	// Get current profile picture id only
+	// if it's meant to be updated, that is,
+	// the value for user.ProfilePictureId is
+	// not empty.
+	var oldImageId string
+
+	if user.ProfilePictureId != "" {
+		row := tx.QueryRowContext(rootCtx, getUserProfilePictureIdById, sql.Named(userId, id))
+
+		var nullableImageId sql.NullString
+
+		err = row.Scan(&nullableImageId)
+		if err != nil {
+			um.logger.Error("Update user - select current picture id", err)
+			return models.User{}, "", models.ErrDatabaseServerFail
+		}
+
+		if nullableImageId.Valid {
+			oldImageId = nullableImageId.String
+		}
+	}
+
+	_, err = tx.ExecContext(
+		rootCtx,
+		query,
+		values...,
+	)
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			err = models.ErrUserNameExists
+		} else {
+			err = models.ErrDatabaseServerFail
+			um.logger.Error(
+				"Update user", err,
+				"userId", id,
+				"name", user.Name,
+				"birthdate", user.Birthdate,
+				"profilePictureId", user.ProfilePictureId,
+			)
+		}
+
+		return models.User{}, "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		um.logger.Error("Update user - close transaction", err)
+		return models.User{}, "", models.ErrDatabaseServerFail
+	}
+
+	return user, oldImageId, nil
+}
+
+func (um *UserManager) ChangePassword(id int, currentPass, newPass string) error {
+	var hashedPassword string
+
+	if currentPass == "" || newPass == "" {
+		return models.ErrUserPasswordEmpty
+	}
+
+	row := um.db.QueryRowContext(
+		rootCtx,
+		getUserPasswordById,
+		sql.Named(userId, id),
+	)
+
+	err := row.Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecords
+		}
+
+		um.logger.Error("Change user password", err, "userId", id)
+		return models.ErrDatabaseServerFail
+	}
+
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(currentPass))
+	if !isValidPassword {
+		return models.ErrPasswordMismatch
+	}
+
+	newHashedPass, err := um.hasher.HashPassword([]byte(newPass))
+	if err != nil {
+		return hashing.ErrPasswordHashingFail
+	}
+
+	_, err = um.db.ExecContext(
+		rootCtx,
+		updateUserPassword,
+		sql.Named(userPassword, string(newHashedPass)),
+		sql.Named(userId, id),
+	)
+	if err != nil {
+		return models.ErrDatabaseServerFail
+	}
+
+	return nil
+}
+
+// Deletes the account of the user identified by id once its password
+// is confirmed, the data of the user is handled according to policy.
+//
+// It returns the id of the profile picture of the user (if any),
+// so the caller can archive it.
+func (um *UserManager) Delete(id int, password string, policy models.DeletionPolicy) (string, error) {
+	if password == "" {
+		return "", models.ErrUserPasswordEmpty
+	}
+
+	if !policy.IsValid() {
+		return "", models.ErrUserDeletionPolicyNotValid
+	}
+
+	tx, err := um.db.BeginTx(rootCtx, &sql.TxOptions{})
+	if err != nil {
+		um.logger.Error("Delete user - begin transaction", err)
+		return "", models.ErrDatabaseServerFail
+	}
+	defer tx.Rollback()
+
+	var hashedPassword string
+	var nullableImageId sql.NullString
+
+	row := tx.QueryRowContext(
+		rootCtx,
+		getUserPasswordAndProfilePictureIdById,
+		sql.Named(userId, id),
+	)
+
+	err = row.Scan(&hashedPassword, &nullableImageId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrNoRecords
+		}
+
+		um.logger.Error("Delete user", err, "userId", id)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	isValidPassword, _ := um.hasher.VerifyPassword([]byte(hashedPassword), []byte(password))
+	if !isValidPassword {
+		return "", models.ErrPasswordMismatch
+	}
+
+	switch policy {
+	case models.DeletionPolicyDelete:
+		// The rows referencing the user go first.
+		for _, query := range []string{deleteMessagesByUserId, deleteParticipationsByUserId} {
+			_, err = tx.ExecContext(rootCtx, query, sql.Named(joinUserId, id))
+			if err != nil {
+				um.logger.Error("Delete user - delete references", err, "userId", id)
+				return "", models.ErrDatabaseServerFail
+			}
+		}
+
+		_, err = tx.ExecContext(rootCtx, deleteUser, sql.Named(userId, id))
+
+	case models.DeletionPolicyTombstone:
+		tombstone := models.TombstoneUser(id)
+
+		_, err = tx.ExecContext(
+			rootCtx,
+			tombstoneUser,
+			sql.Named(userName, tombstone.Name),
+			sql.Named(userPassword, tombstone.Password),
+			sql.Named(userBirthdate, tombstone.Birthdate),
+			sql.Named(userId, id),
+		)
+	}
+	if err != nil {
+		um.logger.Error("Delete user", err, "userId", id, "policy", policy)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		um.logger.Error("Delete user - close transaction", err)
+		return "", models.ErrDatabaseServerFail
+	}
+
+	return nullableImageId.String, nil
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Edwing123/udem-chat-app/pkg/validations/validator"
+)
+
+// Reports whether the birthdate has the format `UserBirthdateFormat`.
+func IsValidBirthdateFormat(birthdate string) bool {
+	return validator.MatchesLayout(birthdate, UserBirthdateFormat)
+}
+
+// Reports whether the name is reserved for the users deleted with
+// `DeletionPolicyTombstone`, the names are compared without case.
+func IsReservedUserName(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), DeletedUserNamePrefix)
+}
+
+// Checks the user to be created, the handlers report every
+// error of the returned validator to the client.
+func CheckNewUser(user User) *validator.Validator {
+	v := validator.New()
+
+	v.Check(validator.NotBlank(user.Name), "name", ErrUserNameEmpty)
+	v.Check(validator.MaxChars(user.Name, UserNameMaxLength), "name", ErrUserNameExceedsMaxLength)
+	v.Check(!IsReservedUserName(user.Name), "name", ErrUserNameReserved)
+	v.Check(user.Password != "", "password", ErrUserPasswordEmpty)
+	v.Check(user.Birthdate != "", "birthdate", ErrUserBirthdateEmpty)
+	v.Check(IsValidBirthdateFormat(user.Birthdate), "birthdate", ErrUserBirthdateBadFormat)
+
+	return v
+}
+
+// Checks the credentials of the user logging in.
+func CheckLogin(user User) *validator.Validator {
+	v := validator.New()
+
+	v.Check(validator.NotBlank(user.Name), "name", ErrUserNameEmpty)
+	v.Check(validator.MaxChars(user.Name, UserNameMaxLength), "name", ErrUserNameExceedsMaxLength)
+	v.Check(user.Password != "", "password", ErrUserPasswordEmpty)
+
+	return v
+}
+
+// Checks the fields of the user to be updated, the empty
+// fields are not updated, so they're not checked.
+func CheckUserUpdate(user User) *validator.Validator {
+	v := validator.New()
+
+	if user.Name != "" {
+		v.Check(validator.NotBlank(user.Name), "name", ErrUserNameEmpty)
+		v.Check(validator.MaxChars(user.Name, UserNameMaxLength), "name", ErrUserNameExceedsMaxLength)
+		v.Check(!IsReservedUserName(user.Name), "name", ErrUserNameReserved)
+	}
+
+	if user.Birthdate != "" {
+		v.Check(IsValidBirthdateFormat(user.Birthdate), "birthdate", ErrUserBirthdateBadFormat)
+	}
+
+	if user.ProfilePictureId != "" {
+		v.Check(
+			validator.MaxChars(user.ProfilePictureId, UserProfilePictureIdLength),
+			"profilePictureId",
+			ErrUserProfilePictureIdNotValidLength,
+		)
+	}
+
+	return v
+}
+
+// Validates the user to be created, it's shared
+// by the implementations of `UserManager`.
+func ValidateNewUser(user User) error {
+	return CheckNewUser(user).Err()
+}
+
+// Validates the credentials of the user logging in, it's
+// shared by the implementations of `UserManager`.
+func ValidateLogin(user User) error {
+	return CheckLogin(user).Err()
+}
+
+// Validates the fields of the user to be updated, it's
+// shared by the implementations of `UserManager`.
+func ValidateUserUpdate(user User) error {
+	return CheckUserUpdate(user).Err()
+}
+
+// DeletionPolicy selects what happens to the data
+// of a user when the user deletes its account.
+type DeletionPolicy string
+
+const (
+	// The user row, its messages and its participations
+	// in conversations are removed.
+	DeletionPolicyDelete DeletionPolicy = "delete"
+
+	// The user row is anonymised (see `TombstoneUser`) and
+	// its messages and participations in conversations are
+	// kept, so the conversations of the other users stay intact.
+	DeletionPolicyTombstone DeletionPolicy = "tombstone"
+)
+
+// Reports whether the policy is one of the known policies.
+func (p DeletionPolicy) IsValid() bool {
+	return p == DeletionPolicyDelete || p == DeletionPolicyTombstone
+}
+
+// Returns the anonymised version of the user identified by id, it
+// has no password, so nobody can log in as the tombstoned user.
+func TombstoneUser(id int) User {
+	return User{
+		Id:        id,
+		Name:      fmt.Sprintf(DeletedUserNameFormat, id),
+		Birthdate: DeletedUserBirthdate,
+	}
+}
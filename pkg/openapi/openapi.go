@@ -0,0 +1,139 @@
+// Package openapi builds OpenAPI 3 documents, the schemas of the
+// request and response bodies are generated from their Go types
+// and the paths from the paths of the Fiber routes.
+//
+// Only the parts of the specification used by the API are covered.
+package openapi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Version of the specification followed by the documents.
+const Version = "3.0.3"
+
+// Document is the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations of a path by HTTP method (in lower case).
+type PathItem map[string]*Operation
+
+// Operation describes a route.
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter describes a path or query parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request by content type.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response by content type.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds the schemas referenced by the operations.
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes a way of authenticating the requests.
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Creates an empty document.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+		},
+	}
+}
+
+// Adds the operation of the method to the path.
+func (d *Document) AddOperation(path string, method string, operation *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// Matches the parameters of the Fiber paths along with their constraint.
+var paramPattern = regexp.MustCompile(`:(\w+)(?:<(\w+)>)?`)
+
+// Converts the path of a Fiber route (`/users/:id<int>`) to an
+// OpenAPI path (`/users/{id}`), the parameters are returned
+// with the schema matching their constraint.
+func Path(fiberPath string) (string, []Parameter) {
+	var parameters []Parameter
+
+	path := paramPattern.ReplaceAllStringFunc(fiberPath, func(param string) string {
+		match := paramPattern.FindStringSubmatch(param)
+		name, constraint := match[1], match[2]
+
+		schema := &Schema{Type: "string"}
+
+		switch constraint {
+		case "int":
+			schema = &Schema{Type: "integer"}
+		case "guid":
+			schema = &Schema{Type: "string", Format: "uuid"}
+		}
+
+		parameters = append(parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+
+		return "{" + name + "}"
+	})
+
+	return path, parameters
+}
@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPath(t *testing.T) {
+	path, parameters := Path("/conversations/:id<int>/images/:imageId<guid>/:name")
+
+	if path != "/conversations/{id}/images/{imageId}/{name}" {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	expected := []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}},
+		{Name: "imageId", In: "path", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}},
+		{Name: "name", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+	}
+
+	if !reflect.DeepEqual(parameters, expected) {
+		t.Errorf("expected parameters %+v, got %+v", expected, parameters)
+	}
+}
+
+type node struct {
+	Name     string    `json:"name"`
+	Children []node    `json:"children,omitempty"`
+	Parent   *node     `json:"parent,omitempty"`
+	Created  time.Time `json:"created"`
+	Secret   string    `json:"-"`
+}
+
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	document := New(Info{Title: "test", Version: "1"})
+
+	schema := document.SchemaOf(envelope[[]node]{})
+
+	// The generic types are described in place.
+	ref := &Schema{Ref: "#/components/schemas/node"}
+	expected := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"data": {Type: "array", Items: ref}},
+		Required:   []string{"data"},
+	}
+
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("expected schema %+v, got %+v", expected, schema)
+	}
+
+	// The named types are referenced, even by themselves.
+	expected = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":     {Type: "string"},
+			"children": {Type: "array", Items: ref},
+			"parent":   ref,
+			"created":  {Type: "string", Format: "date-time"},
+		},
+		Required: []string{"name", "created"},
+	}
+
+	if !reflect.DeepEqual(document.Components.Schemas["node"], expected) {
+		t.Errorf("expected component %+v, got %+v", expected, document.Components.Schemas["node"])
+	}
+}
@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema describes the type of a value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Returns the schema of the type of v as encoded by `encoding/json`,
+// the named structs are added to the components and referenced,
+// except the generic ones, which are described in place.
+func (d *Document) SchemaOf(v any) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	// A nil interface, for example the value of `any`.
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+
+	// Encoded with its message.
+	case t.Implements(errorType) && t.Kind() == reflect.Interface:
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		// Encoded in base64.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+
+	case reflect.Pointer:
+		schema := *d.schema(t.Elem())
+		if schema.Ref != "" {
+			return &schema
+		}
+
+		schema.Nullable = true
+		return &schema
+
+	case reflect.Struct:
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return d.object(t)
+		}
+
+		name := t.Name()
+
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Added before describing it, the type could reference itself.
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// Any value, for example the values of the interfaces.
+	return &Schema{}
+}
+
+// Describes the fields of the struct, the fields that
+// are omitted when empty are not required.
+func (d *Document) object(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		// The fields of the embedded structs are promoted.
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := d.object(field.Type)
+
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = d.schema(field.Type)
+
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
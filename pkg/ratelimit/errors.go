@@ -0,0 +1,11 @@
+package ratelimit
+
+import (
+	"net/http"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrRateLimited = codes.NewCodeWithStatus("rate_limited", http.StatusTooManyRequests)
+)
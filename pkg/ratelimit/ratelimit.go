@@ -0,0 +1,207 @@
+// Package ratelimit limits the number of requests a client can make
+// within a window of time, the counters are kept in a `fiber.Storage`
+// (Redis in production) and in memory while the storage is failing.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+	"golang.org/x/exp/slog"
+)
+
+// Number of groups the counters are split into, the
+// counters of different groups are updated concurrently.
+const shardCount = 32
+
+// Increments the counter of KEYS[1] and sets its expiration
+// to ARGV[1] milliseconds when it's created, in a single step.
+const incrementScript = `
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`
+
+// Rule is the number of requests allowed within a window.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Result is the state of a counter after taking a request from it.
+type Result struct {
+	// Whether the request is within the limit.
+	Allowed bool
+
+	Limit     int
+	Remaining int
+
+	// Time left before the counter is reset.
+	Reset time.Duration
+}
+
+// Limiter counts the requests of the clients using fixed windows.
+type Limiter struct {
+	storage fiber.Storage
+
+	// Set when the storage is Redis, whose counters
+	// are incremented atomically by the server.
+	redis *redis.Storage
+
+	shards [shardCount]shard
+
+	// Whether the storage failed the last time it was used,
+	// only the changes of state are logged.
+	failing atomic.Bool
+
+	logger *slog.Logger
+}
+
+// Group of counters sharing a lock.
+type shard struct {
+	// Serializes the updates of the counters of the group, the
+	// ones of the storages other than Redis are read, modified
+	// and written back.
+	mu sync.Mutex
+
+	// Counters used while the storage is failing, by key.
+	fallback map[string]counter
+}
+
+// Represents a counter kept in memory.
+type counter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// Creates a limiter which keeps the counters in the provided storage.
+func New(storage fiber.Storage, logger *slog.Logger) *Limiter {
+	l := &Limiter{
+		storage: storage,
+		logger:  logger,
+	}
+
+	l.redis, _ = storage.(*redis.Storage)
+
+	for i := range l.shards {
+		l.shards[i].fallback = map[string]counter{}
+	}
+
+	return l
+}
+
+// Returns the group of the counter of the key.
+func (l *Limiter) shard(key string) *shard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	return &l.shards[hash.Sum32()%shardCount]
+}
+
+// Increments the counter of the key in the storage,
+// it returns the count after the increment.
+func (l *Limiter) increment(key string, ttl time.Duration) (int, error) {
+	if l.redis != nil {
+		// The expiration can't be shorter than a millisecond.
+		ms := ttl.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+
+		return l.redis.Conn().Eval(context.Background(), incrementScript, []string{key}, ms).Int()
+	}
+
+	s := l.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := l.storage.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	if raw != nil {
+		count, err = strconv.Atoi(string(raw))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	count++
+
+	err = l.storage.Set(key, []byte(strconv.Itoa(count)), ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Increments the counter of the key in memory,
+// it returns the count after the increment.
+func (l *Limiter) incrementFallback(key string, ttl time.Duration, now time.Time) int {
+	s := l.shard(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop the expired counters, so the map doesn't grow forever.
+	for k, c := range s.fallback {
+		if now.After(c.expiresAt) {
+			delete(s.fallback, k)
+		}
+	}
+
+	c := s.fallback[key]
+	c.count++
+	c.expiresAt = now.Add(ttl)
+	s.fallback[key] = c
+
+	return c.count
+}
+
+// Counts a request of the client identified by key against the rule,
+// different rules must use different keys (e.g. by prefixing them).
+func (l *Limiter) Take(key string, rule Rule) Result {
+	now := time.Now()
+
+	// Start of the current window.
+	start := now.Truncate(rule.Window)
+	reset := start.Add(rule.Window).Sub(now)
+	windowKey := fmt.Sprintf("ratelimit_%s_%d", key, start.Unix())
+
+	count, err := l.increment(windowKey, reset)
+	if err != nil {
+		if l.failing.CompareAndSwap(false, true) {
+			l.logger.Error("Rate limit storage failed, using memory", err)
+		}
+
+		count = l.incrementFallback(windowKey, reset, now)
+	} else if l.failing.CompareAndSwap(true, false) {
+		l.logger.Info("rate limit storage recovered")
+	}
+
+	remaining := rule.Limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   count <= rule.Limit,
+		Limit:     rule.Limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
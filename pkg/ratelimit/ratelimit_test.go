@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"golang.org/x/exp/slog"
+)
+
+// Storage whose operations always fail.
+type brokenStorage struct{}
+
+var errBroken = errors.New("broken storage")
+
+func (brokenStorage) Get(string) ([]byte, error)              { return nil, errBroken }
+func (brokenStorage) Set(string, []byte, time.Duration) error { return errBroken }
+func (brokenStorage) Delete(string) error                     { return errBroken }
+func (brokenStorage) Reset() error                            { return errBroken }
+func (brokenStorage) Close() error                            { return nil }
+
+// Returns the in-memory storage used by the sessions by default.
+func newMemoryStorage() fiber.Storage {
+	return session.New(session.Config{}).Storage
+}
+
+func newLimiter(storage fiber.Storage) *Limiter {
+	return New(storage, slog.New(slog.NewTextHandler(io.Discard)))
+}
+
+func TestLimiter(t *testing.T) {
+	for _, broken := range []bool{false, true} {
+		storage := newMemoryStorage()
+		if broken {
+			storage = brokenStorage{}
+		}
+
+		l := newLimiter(storage)
+
+		rule := Rule{Limit: 2, Window: time.Hour}
+
+		for i := 1; i <= 3; i++ {
+			result := l.Take("user_1", rule)
+
+			if result.Allowed != (i <= rule.Limit) {
+				t.Errorf("broken=%t, request %d: expected allowed to be %t", broken, i, i <= rule.Limit)
+			}
+
+			if result.Limit != rule.Limit || result.Reset <= 0 || result.Reset > rule.Window {
+				t.Errorf("broken=%t, request %d: unexpected result %+v", broken, i, result)
+			}
+		}
+
+		// Other clients have their own counters.
+		if result := l.Take("user_2", rule); !result.Allowed || result.Remaining != 1 {
+			t.Errorf("broken=%t: expected 1 remaining request, got %+v", broken, result)
+		}
+	}
+}
+
+func TestLimiterConcurrentRequests(t *testing.T) {
+	for _, broken := range []bool{false, true} {
+		storage := newMemoryStorage()
+		if broken {
+			storage = brokenStorage{}
+		}
+
+		l := newLimiter(storage)
+
+		rule := Rule{Limit: 10, Window: time.Hour}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		allowed := 0
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if l.Take("user_1", rule).Allowed {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		// No request is lost between reading and writing a counter.
+		if allowed != rule.Limit {
+			t.Errorf("broken=%t: expected %d allowed requests, got %d", broken, rule.Limit, allowed)
+		}
+	}
+}
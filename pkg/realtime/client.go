@@ -0,0 +1,131 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// Time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// Time allowed to read the next pong message from the peer.
+	pongWait = 60 * time.Second
+
+	// Period of the pings sent to the peer, it must be less than pongWait.
+	pingPeriod = pongWait * 9 / 10
+
+	// Maximum size of the messages read from the peer, the clients
+	// don't send anything besides the control messages.
+	maxMessageSize = 512
+
+	// Number of events buffered per connection, when it's
+	// full the client is considered too slow and is dropped.
+	sendBufferSize = 64
+)
+
+// Represents a WebSocket connection of a user.
+type client struct {
+	userId int
+	conn   *websocket.Conn
+
+	// Id of the session the connection was opened with.
+	sessionId string
+
+	// Payloads of the events pending to be written.
+	send chan []byte
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newClient(userId int, sessionId string, conn *websocket.Conn) *client {
+	return &client{
+		userId:    userId,
+		conn:      conn,
+		sessionId: sessionId,
+		send:      make(chan []byte, sendBufferSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// Queues the payload to be written, if the queue is full
+// the client is closed and false is returned.
+func (c *client) push(payload []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	case c.send <- payload:
+		return true
+	default:
+		c.close()
+		return false
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// Reads from the connection until it's closed, which is needed
+// to process the control messages (ping, pong and close).
+func (c *client) readPump() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Writes the queued payloads and the pings to the
+// connection until the client is closed.
+func (c *client) writePump(logger *slog.Logger) {
+	ticker := time.NewTicker(pingPeriod)
+
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			err := c.conn.WriteMessage(websocket.TextMessage, payload)
+			if err != nil {
+				logger.Info("websocket write fail", "userId", c.userId, "err", err)
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				return
+			}
+
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			)
+			return
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package realtime
+
+// Types of the events pushed to the clients.
+const (
+	EventMessageNew        = "message_new"
+	EventParticipantJoined = "participant_joined"
+	EventParticipantLeft   = "participant_left"
+	EventConversationEnded = "conversation_ended"
+	EventMatchFound        = "match_found"
+)
+
+// Event represents a message pushed to the clients.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// ParticipantEvent is the data of the events
+// about participants joining or leaving a conversation.
+type ParticipantEvent struct {
+	ConversationId int `json:"conversationId"`
+	UserId         int `json:"userId"`
+}
+
+// ConversationEvent is the data of the
+// events about the state of a conversation.
+type ConversationEvent struct {
+	ConversationId int `json:"conversationId"`
+
+	// Why the conversation ended, for example "expired".
+	Reason string `json:"reason,omitempty"`
+}
@@ -0,0 +1,206 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slog"
+)
+
+// Hub keeps track of the WebSocket connections
+// of the users and pushes events to them.
+type Hub struct {
+	mu sync.RWMutex
+
+	// Connections of each user, a user can be
+	// connected from more than one client.
+	clients map[int]map[*client]struct{}
+
+	// Whether the hub was shut down, the new
+	// connections are rejected afterwards.
+	closed bool
+
+	// Counts the open connections, so the
+	// shutdown can wait for them to finish.
+	connections sync.WaitGroup
+
+	upgrader websocket.FastHTTPUpgrader
+
+	logger *slog.Logger
+}
+
+// Creates a hub which will log messages using the provided logger.
+func New(logger *slog.Logger) *Hub {
+	return &Hub{
+		clients: map[int]map[*client]struct{}{},
+		upgrader: websocket.FastHTTPUpgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		logger: logger,
+	}
+}
+
+// Upgrades the request to a WebSocket connection owned by
+// the user identified by userId, the events published to the
+// user will be pushed through the connection until it's closed.
+// The connection belongs to the session identified by sessionId,
+// see `Hub.Disconnect`.
+func (h *Hub) Upgrade(c *fiber.Ctx, userId int, sessionId string) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.Context()) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	h.mu.RLock()
+	closed := h.closed
+	h.mu.RUnlock()
+
+	if closed {
+		return fiber.ErrServiceUnavailable
+	}
+
+	return h.upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+		client := newClient(userId, sessionId, conn)
+
+		// The hub could've been shut down during the upgrade.
+		if !h.register(client) {
+			conn.Close()
+			return
+		}
+
+		defer h.connections.Done()
+
+		writerDone := make(chan struct{})
+
+		go func() {
+			client.writePump(h.logger)
+			close(writerDone)
+		}()
+
+		client.readPump()
+		h.unregister(client)
+
+		// The connection must not be used once
+		// this function returns, so wait for the writer.
+		<-writerDone
+	})
+}
+
+// Pushes the event to every connection of the users identified by userIds.
+func (h *Hub) Publish(userIds []int, event Event) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		h.logger.Error("Marshal event", err, "type", event.Type)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userId := range userIds {
+		for client := range h.clients[userId] {
+			if !client.push(payload) {
+				h.logger.Warn("Drop slow client", "userId", userId)
+			}
+		}
+	}
+}
+
+// Closes the connections of the user identified by userId except the
+// ones of the session identified by exceptSessionId, an empty
+// exceptSessionId closes every connection. It's meant for the users
+// whose sessions were revoked, for example once the account is deleted.
+func (h *Hub) Disconnect(userId int, exceptSessionId string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients[userId] {
+		if exceptSessionId == "" || client.sessionId != exceptSessionId {
+			client.close()
+		}
+	}
+}
+
+// Closes the connections of the user identified by userId
+// opened with the session identified by sessionId.
+func (h *Hub) DisconnectSession(userId int, sessionId string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients[userId] {
+		if client.sessionId == sessionId {
+			client.close()
+		}
+	}
+}
+
+// Shuts down the hub, the connections are closed and the
+// new ones are rejected. It waits for the connections to
+// finish until the context is done, returning its error.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+
+	h.closed = true
+
+	for _, clients := range h.clients {
+		for client := range clients {
+			client.close()
+		}
+	}
+
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		h.connections.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Registers the client, it returns false if the hub
+// was shut down, in which case the client is not added.
+func (h *Hub) register(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+
+	h.connections.Add(1)
+
+	clients, ok := h.clients[c.userId]
+	if !ok {
+		clients = map[*client]struct{}{}
+		h.clients[c.userId] = clients
+	}
+
+	clients[c] = struct{}{}
+
+	return true
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.clients[c.userId]
+	delete(clients, c)
+
+	if len(clients) == 0 {
+		delete(h.clients, c.userId)
+	}
+
+	c.close()
+}
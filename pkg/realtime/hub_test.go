@@ -0,0 +1,252 @@
+package realtime
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"golang.org/x/exp/slog"
+)
+
+// Serves the hub from an in-memory listener, the connections are owned
+// by the user of the query `userId` and the session of the query `session`.
+func newTestServer(t *testing.T) (*Hub, *fasthttputil.InmemoryListener) {
+	t.Helper()
+
+	hub := New(slog.New(slog.NewTextHandler(io.Discard)))
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		userId, _ := strconv.Atoi(c.Query("userId"))
+		return hub.Upgrade(c, userId, c.Query("session"))
+	})
+
+	listener := fasthttputil.NewInmemoryListener()
+
+	go app.Listener(listener)
+
+	t.Cleanup(func() {
+		app.Shutdown()
+	})
+
+	return hub, listener
+}
+
+// Returns a dialer connecting through the in-memory listener.
+func newDialer(listener *fasthttputil.InmemoryListener) websocket.Dialer {
+	return websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return listener.Dial()
+		},
+	}
+}
+
+// Connects as the user identified by userId and waits
+// for the hub to register the connection.
+func connect(t *testing.T, hub *Hub, listener *fasthttputil.InmemoryListener, userId int) *websocket.Conn {
+	t.Helper()
+
+	return connectSession(t, hub, listener, userId, "")
+}
+
+// Same as `connect` but the connection belongs to
+// the session identified by sessionId.
+func connectSession(
+	t *testing.T,
+	hub *Hub,
+	listener *fasthttputil.InmemoryListener,
+	userId int,
+	sessionId string,
+) *websocket.Conn {
+	t.Helper()
+
+	dialer := newDialer(listener)
+	connected := hub.connected(userId)
+
+	conn, _, err := dialer.Dial("ws://hub/ws?userId="+strconv.Itoa(userId)+"&session="+sessionId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	deadline := time.Now().Add(time.Second)
+
+	for hub.connected(userId) == connected {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not registered")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return conn
+}
+
+// Returns the number of connections of the user.
+func (h *Hub) connected(userId int) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients[userId])
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) Event {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var event Event
+
+	err := conn.ReadJSON(&event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
+
+func TestHubPublish(t *testing.T) {
+	hub, listener := newTestServer(t)
+
+	first := connect(t, hub, listener, 1)
+	second := connect(t, hub, listener, 2)
+
+	hub.Publish([]int{1}, Event{Type: EventMessageNew, Data: "first"})
+	hub.Publish([]int{1, 2}, Event{Type: EventConversationEnded, Data: "both"})
+
+	// The events are received in order and only by their users.
+	for _, expected := range []string{EventMessageNew, EventConversationEnded} {
+		event := readEvent(t, first)
+		if event.Type != expected {
+			t.Errorf("first: expected event %q, got %q", expected, event.Type)
+		}
+	}
+
+	event := readEvent(t, second)
+	if event.Type != EventConversationEnded {
+		t.Errorf("second: expected event %q, got %q", EventConversationEnded, event.Type)
+	}
+}
+
+func TestHubDropSlowClient(t *testing.T) {
+	hub := New(slog.New(slog.NewTextHandler(io.Discard)))
+
+	// The client is not connected, so its queue is never written.
+	client := newClient(1, "", nil)
+	hub.register(client)
+
+	for i := 0; i < sendBufferSize; i++ {
+		hub.Publish([]int{1}, Event{Type: EventMessageNew})
+	}
+
+	select {
+	case <-client.done:
+		t.Fatal("client closed before its queue was full")
+	default:
+	}
+
+	hub.Publish([]int{1}, Event{Type: EventMessageNew})
+
+	select {
+	case <-client.done:
+	default:
+		t.Fatal("expected the slow client to be closed")
+	}
+}
+
+func TestHubShutdown(t *testing.T) {
+	hub, listener := newTestServer(t)
+
+	conn := connect(t, hub, listener, 1)
+
+	// The connection is read while the hub shuts
+	// down, the in-memory connections don't buffer.
+	closeErr := make(chan error, 1)
+
+	go func() {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err := conn.ReadMessage()
+		closeErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err := hub.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// The connection is closed normally.
+	err = <-closeErr
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("expected a normal close, got %v", err)
+	}
+
+	// The new connections are rejected.
+	dialer := newDialer(listener)
+
+	_, res, err := dialer.Dial("ws://hub/ws?userId=2", nil)
+	if err == nil {
+		t.Fatal("expected the connection to be rejected")
+	}
+
+	if res == nil || res.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %v", fiber.StatusServiceUnavailable, res)
+	}
+}
+
+// Waits for the connection to be closed normally.
+func expectClosed(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("expected a normal close, got %v", err)
+	}
+}
+
+func TestHubDisconnect(t *testing.T) {
+	hub, listener := newTestServer(t)
+
+	kept := connectSession(t, hub, listener, 1, "kept")
+	revoked := connectSession(t, hub, listener, 1, "revoked")
+	other := connect(t, hub, listener, 2)
+
+	hub.Disconnect(1, "kept")
+	expectClosed(t, revoked)
+
+	// The connections of the other session and
+	// of the other users keep their events.
+	hub.Publish([]int{1, 2}, Event{Type: EventMessageNew})
+
+	readEvent(t, kept)
+	readEvent(t, other)
+
+	hub.Disconnect(1, "")
+	expectClosed(t, kept)
+}
+
+func TestHubDisconnectSession(t *testing.T) {
+	hub, listener := newTestServer(t)
+
+	kept := connectSession(t, hub, listener, 1, "kept")
+	revoked := connectSession(t, hub, listener, 1, "revoked")
+
+	hub.DisconnectSession(1, "revoked")
+	expectClosed(t, revoked)
+
+	hub.Publish([]int{1}, Event{Type: EventMessageNew})
+	readEvent(t, kept)
+}
@@ -0,0 +1,12 @@
+package tokens
+
+import (
+	"net/http"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrTokenNotValid = codes.NewCodeWithStatus("token_not_valid", http.StatusUnauthorized)
+	ErrTokenExpired  = codes.NewCodeWithStatus("token_expired", http.StatusUnauthorized)
+)
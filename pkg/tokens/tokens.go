@@ -0,0 +1,439 @@
+// Package tokens implements the bearer tokens authentication, it issues
+// short-lived signed access tokens along with refresh tokens, which are
+// rotated every time they're used to get a new pair of tokens.
+package tokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/storage/redis"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The issuer of the access tokens.
+const issuer = "nameless"
+
+// Deletes KEYS[1] and returns its value in a single step,
+// the value is an empty string if the key doesn't exist.
+const takeScript = `
+local value = redis.call("GET", KEYS[1])
+if not value then
+	return ""
+end
+redis.call("DEL", KEYS[1])
+return value
+`
+
+// Manager issues and verifies the tokens, the refresh
+// tokens are kept in the provided storage.
+type Manager struct {
+	// Serializes the updates of the indexes of the refresh
+	// tokens, and the use of the refresh tokens when the
+	// storage isn't Redis, so they can't be used twice.
+	mu sync.Mutex
+
+	storage fiber.Storage
+
+	// Set when the storage is Redis, whose refresh tokens
+	// are taken atomically by the server, so they can't be
+	// used twice even through different servers.
+	redis *redis.Storage
+
+	options Options
+}
+
+// Options of the manager.
+type Options struct {
+	// Key used to sign the access tokens.
+	Secret []byte
+
+	// Time the access tokens are valid for.
+	AccessTTL time.Duration
+
+	// Time the refresh tokens are valid for.
+	RefreshTTL time.Duration
+}
+
+// Pair is the result of authenticating with tokens.
+type Pair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+
+	// Always "Bearer".
+	TokenType string `json:"tokenType"`
+
+	// Seconds the access token is valid for.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+// The data stored for each refresh token.
+type refreshData struct {
+	UserId int `json:"userId"`
+}
+
+// Creates a manager which keeps the refresh
+// tokens in the provided storage.
+func New(storage fiber.Storage, options Options) *Manager {
+	m := &Manager{
+		storage: storage,
+		options: options,
+	}
+
+	m.redis, _ = storage.(*redis.Storage)
+
+	return m
+}
+
+// Returns the id of the refresh token, only the id is stored,
+// so the tokens can't be recovered from the storage.
+func tokenId(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func refreshTokenKey(id string) string {
+	return "refresh_token_" + id
+}
+
+func userTokensKey(userId int) string {
+	return fmt.Sprintf("user_refresh_tokens_%d", userId)
+}
+
+// Reads the ids of the refresh tokens of the
+// user, the caller must hold the lock.
+func (m *Manager) readIndex(userId int) ([]string, error) {
+	raw, err := m.storage.Get(userTokensKey(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+
+	if raw == nil {
+		return ids, nil
+	}
+
+	err = json.Unmarshal(raw, &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Writes the ids of the refresh tokens of the
+// user, the caller must hold the lock.
+func (m *Manager) writeIndex(userId int, ids []string) error {
+	if len(ids) == 0 {
+		return m.storage.Delete(userTokensKey(userId))
+	}
+
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	return m.storage.Set(userTokensKey(userId), raw, m.options.RefreshTTL)
+}
+
+// Issues a new pair of tokens for the user, the caller must hold the lock.
+func (m *Manager) issue(userId int) (Pair, error) {
+	buffer := make([]byte, 32)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	refreshToken := base64.RawURLEncoding.EncodeToString(buffer)
+	id := tokenId(refreshToken)
+
+	raw, err := json.Marshal(refreshData{UserId: userId})
+	if err != nil {
+		return Pair{}, err
+	}
+
+	err = m.storage.Set(refreshTokenKey(id), raw, m.options.RefreshTTL)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	// Keep the ids of the tokens still stored.
+	ids, err := m.readIndex(userId)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	alive := []string{id}
+
+	for _, storedId := range ids {
+		raw, err := m.storage.Get(refreshTokenKey(storedId))
+		if err == nil && raw != nil {
+			alive = append(alive, storedId)
+		}
+	}
+
+	err = m.writeIndex(userId, alive)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	now := time.Now()
+
+	// The access token carries the id of its refresh
+	// token, so the pair can be told apart from the
+	// other pairs of the user.
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.Itoa(userId),
+		ID:        id,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.options.AccessTTL)),
+	}).SignedString(m.options.Secret)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	return Pair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.options.AccessTTL.Seconds()),
+	}, nil
+}
+
+// Deletes the refresh token identified by id, the caller must hold the lock.
+func (m *Manager) revoke(userId int, id string) error {
+	err := m.storage.Delete(refreshTokenKey(id))
+	if err != nil {
+		return err
+	}
+
+	return m.unindex(userId, id)
+}
+
+// Removes the refresh token identified by id from the
+// index of the user, the caller must hold the lock.
+func (m *Manager) unindex(userId int, id string) error {
+	ids, err := m.readIndex(userId)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+
+	for _, storedId := range ids {
+		if storedId != id {
+			remaining = append(remaining, storedId)
+		}
+	}
+
+	return m.writeIndex(userId, remaining)
+}
+
+// Decodes the data of a refresh token, it fails with
+// `ErrTokenNotValid` if the token is unknown (raw is nil).
+func decodeRefreshData(raw []byte) (refreshData, error) {
+	if raw == nil {
+		return refreshData{}, ErrTokenNotValid
+	}
+
+	var data refreshData
+
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return refreshData{}, err
+	}
+
+	return data, nil
+}
+
+// Reads the data of the refresh token identified by id, it
+// fails with `ErrTokenNotValid` if the token is unknown.
+func (m *Manager) lookup(id string) (refreshData, error) {
+	raw, err := m.storage.Get(refreshTokenKey(id))
+	if err != nil {
+		return refreshData{}, err
+	}
+
+	return decodeRefreshData(raw)
+}
+
+// Deletes the refresh token identified by id and returns its data,
+// it fails with `ErrTokenNotValid` if the token is unknown. Only
+// one of the concurrent calls with the same id gets the data, the
+// caller must hold the lock unless the storage is Redis.
+func (m *Manager) take(id string) (refreshData, error) {
+	key := refreshTokenKey(id)
+
+	if m.redis != nil {
+		value, err := m.redis.Conn().Eval(context.Background(), takeScript, []string{key}).Text()
+		if err != nil {
+			return refreshData{}, err
+		}
+
+		if value == "" {
+			return refreshData{}, ErrTokenNotValid
+		}
+
+		return decodeRefreshData([]byte(value))
+	}
+
+	raw, err := m.storage.Get(key)
+	if err != nil {
+		return refreshData{}, err
+	}
+
+	data, err := decodeRefreshData(raw)
+	if err != nil {
+		return refreshData{}, err
+	}
+
+	return data, m.storage.Delete(key)
+}
+
+// Issues a new pair of tokens for the user identified by userId.
+func (m *Manager) Issue(userId int) (Pair, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.issue(userId)
+}
+
+// Verifies the access token, it returns the id of the user
+// and the id of the refresh token issued along with it.
+//
+// The access token is no longer valid once its refresh token is
+// used or revoked, for example by logging out or by changing the
+// password, even if the access token hasn't expired yet.
+func (m *Manager) Verify(accessToken string) (int, string, error) {
+	var claims jwt.RegisteredClaims
+
+	_, err := jwt.ParseWithClaims(
+		accessToken,
+		&claims,
+		func(*jwt.Token) (any, error) {
+			return m.options.Secret, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			return 0, "", ErrTokenExpired
+		}
+
+		return 0, "", ErrTokenNotValid
+	}
+
+	if !claims.VerifyIssuer(issuer, true) {
+		return 0, "", ErrTokenNotValid
+	}
+
+	userId, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, "", ErrTokenNotValid
+	}
+
+	data, err := m.lookup(claims.ID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if data.UserId != userId {
+		return 0, "", ErrTokenNotValid
+	}
+
+	return userId, claims.ID, nil
+}
+
+// Issues a new pair of tokens in exchange for the refresh
+// token, which can't be used again afterwards.
+func (m *Manager) Refresh(refreshToken string) (Pair, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := tokenId(refreshToken)
+
+	data, err := m.take(id)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	err = m.unindex(data.UserId, id)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	return m.issue(data.UserId)
+}
+
+// Revokes the refresh token, unknown tokens are ignored.
+func (m *Manager) Revoke(refreshToken string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := tokenId(refreshToken)
+
+	data, err := m.lookup(id)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotValid) {
+			return nil
+		}
+
+		return err
+	}
+
+	return m.revoke(data.UserId, id)
+}
+
+// Revokes the refresh token of the user identified by id, the id
+// of the refresh token is the one returned by `Verify`.
+func (m *Manager) RevokeId(userId int, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.revoke(userId, id)
+}
+
+// Revokes every refresh token of the user except the one identified
+// by exceptId, an empty exceptId revokes every refresh token.
+//
+// The access tokens issued along with the revoked refresh
+// tokens are rejected by `Verify` from then on.
+func (m *Manager) RevokeAll(userId int, exceptId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids, err := m.readIndex(userId)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+
+	for _, id := range ids {
+		if id == exceptId {
+			remaining = append(remaining, id)
+			continue
+		}
+
+		err := m.storage.Delete(refreshTokenKey(id))
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.writeIndex(userId, remaining)
+}
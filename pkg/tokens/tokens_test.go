@@ -0,0 +1,142 @@
+package tokens
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// Returns the in-memory storage used by the sessions by default.
+func newMemoryStorage() fiber.Storage {
+	return session.New(session.Config{}).Storage
+}
+
+func newTestManager(accessTTL time.Duration) *Manager {
+	return New(newMemoryStorage(), Options{
+		Secret:     []byte("0123456789abcdef0123456789abcdef"),
+		AccessTTL:  accessTTL,
+		RefreshTTL: time.Hour,
+	})
+}
+
+func TestManager(t *testing.T) {
+	m := newTestManager(time.Minute)
+
+	pair, err := m.Issue(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	userId, id, err := m.Verify(pair.AccessToken)
+	if err != nil || userId != 7 || id == "" {
+		t.Fatalf("expected user 7 and nil error, got user %d and %v", userId, err)
+	}
+
+	_, _, err = m.Verify(pair.AccessToken + "x")
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+
+	// The refresh tokens are rotated.
+	refreshed, err := m.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = m.Refresh(pair.RefreshToken)
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+
+	// The access token goes along with its refresh token.
+	_, _, err = m.Verify(pair.AccessToken)
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+
+	other, err := m.Issue(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, keptId, err := m.Verify(refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = m.RevokeAll(7, keptId)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = m.Refresh(other.RefreshToken)
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+
+	_, _, err = m.Verify(other.AccessToken)
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+
+	err = m.Revoke(refreshed.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = m.Refresh(refreshed.RefreshToken)
+	if err != ErrTokenNotValid {
+		t.Errorf("expected %v, got %v", ErrTokenNotValid, err)
+	}
+}
+
+func TestManagerConcurrentRefresh(t *testing.T) {
+	m := newTestManager(time.Minute)
+
+	pair, err := m.Issue(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	refreshed := 0
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := m.Refresh(pair.RefreshToken)
+			if err == nil {
+				mu.Lock()
+				refreshed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if refreshed != 1 {
+		t.Errorf("expected the refresh token to be used once, got %d", refreshed)
+	}
+}
+
+func TestManagerExpiredToken(t *testing.T) {
+	m := newTestManager(-time.Minute)
+
+	pair, err := m.Issue(7)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, _, err = m.Verify(pair.AccessToken)
+	if err != ErrTokenExpired {
+		t.Errorf("expected %v, got %v", ErrTokenExpired, err)
+	}
+}
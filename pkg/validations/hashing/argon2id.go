@@ -0,0 +1,144 @@
+package hashing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Parameters recommended by the argon2 package.
+const (
+	Argon2idDefaultTime    = 1
+	Argon2idDefaultMemory  = 64 * 1024
+	Argon2idDefaultThreads = 4
+)
+
+const (
+	argon2idSaltLength = 16
+	argon2idKeyLength  = 32
+)
+
+// Argon2id hashes the passwords with argon2id, the hashes
+// are encoded in the PHC string format, for example:
+//
+//	$argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+type Argon2id struct {
+	// Number of passes over the memory.
+	Time uint32
+
+	// Memory used in KiB.
+	Memory uint32
+
+	// Number of threads used.
+	Threads uint8
+}
+
+// The parameters and values decoded from a hash.
+type argon2idHash struct {
+	params Argon2id
+	salt   []byte
+	key    []byte
+}
+
+func decodeArgon2id(hashedPassword []byte) (argon2idHash, error) {
+	parts := strings.Split(string(hashedPassword), "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return argon2idHash{}, fmt.Errorf("argon2id: hash not valid")
+	}
+
+	var version int
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return argon2idHash{}, err
+	}
+
+	if version != argon2.Version {
+		return argon2idHash{}, fmt.Errorf("argon2id: version %d not supported", version)
+	}
+
+	var decoded argon2idHash
+
+	_, err = fmt.Sscanf(
+		parts[3],
+		"m=%d,t=%d,p=%d",
+		&decoded.params.Memory,
+		&decoded.params.Time,
+		&decoded.params.Threads,
+	)
+	if err != nil {
+		return argon2idHash{}, err
+	}
+
+	decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idHash{}, err
+	}
+
+	decoded.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2idHash{}, err
+	}
+
+	return decoded, nil
+}
+
+func (a Argon2id) Name() string {
+	return AlgorithmArgon2id
+}
+
+func (a Argon2id) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, argon2idSaltLength)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, argon2idKeyLength)
+
+	hashedPassword := fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		a.Memory,
+		a.Time,
+		a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return []byte(hashedPassword), nil
+}
+
+func (a Argon2id) Recognizes(hashedPassword []byte) bool {
+	return bytes.HasPrefix(hashedPassword, []byte("$"+AlgorithmArgon2id+"$"))
+}
+
+func (a Argon2id) Verify(hashedPassword, password []byte) bool {
+	decoded, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey(
+		password,
+		decoded.salt,
+		decoded.params.Time,
+		decoded.params.Memory,
+		decoded.params.Threads,
+		uint32(len(decoded.key)),
+	)
+
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1
+}
+
+func (a Argon2id) Outdated(hashedPassword []byte) bool {
+	decoded, err := decodeArgon2id(hashedPassword)
+	return err != nil || decoded.params != a
+}
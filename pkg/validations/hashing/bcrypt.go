@@ -1,19 +1,26 @@
 package hashing
 
 import (
-	"github.com/Edwing123/udem-chat-app/pkg/codes"
+	"bytes"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	ErrPasswordHashingFail = codes.NewCode("password_hashing_fail")
-)
+// The cost used to hash the passwords before
+// the hashing options were configurable.
+const BcryptDefaultCost = 12
+
+// Bcrypt hashes the passwords with bcrypt.
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Name() string {
+	return AlgorithmBcrypt
+}
 
-// HashPassword creates the bcrypt hash of the password.
-// On success it returns the hashed password and a nil error, and on failure,
-// it returns a nil hashed password and non-nil error.
-func HashPassword(password []byte) ([]byte, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword(password, 12)
+func (b Bcrypt) Hash(password []byte) ([]byte, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword(password, b.Cost)
 	if err != nil {
 		return nil, err
 	}
@@ -21,9 +28,17 @@ func HashPassword(password []byte) ([]byte, error) {
 	return hashedPassword, nil
 }
 
-// VerifyPassword compares a bcrypt hashed password with its
-// possible plaintext equivalent. Returns true on success, or false on failure.
-func VerifyPassword(hashedPassword, password []byte) bool {
+func (b Bcrypt) Recognizes(hashedPassword []byte) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if bytes.HasPrefix(hashedPassword, []byte(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b Bcrypt) Verify(hashedPassword, password []byte) bool {
 	err := bcrypt.CompareHashAndPassword(
 		hashedPassword,
 		password,
@@ -31,3 +46,8 @@ func VerifyPassword(hashedPassword, password []byte) bool {
 
 	return err == nil
 }
+
+func (b Bcrypt) Outdated(hashedPassword []byte) bool {
+	cost, err := bcrypt.Cost(hashedPassword)
+	return err != nil || cost != b.Cost
+}
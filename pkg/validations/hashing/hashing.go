@@ -0,0 +1,127 @@
+// Package hashing hashes and verifies the passwords of the users, the
+// hashes are produced by the preferred algorithm, while the hashes
+// produced by any of the supported algorithms can be verified, so the
+// preferred algorithm (or its cost) can change over time.
+package hashing
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+var (
+	ErrPasswordHashingFail = codes.NewCodeWithStatus("password_hashing_fail", http.StatusInternalServerError)
+)
+
+// Operations of the hashers, see `Manager.Observe`.
+const (
+	OperationHash   = "hash"
+	OperationVerify = "verify"
+)
+
+// Observer receives the duration of an operation of
+// the hasher of the algorithm identified by name.
+type Observer func(algorithm, operation string, duration time.Duration)
+
+// Names of the supported algorithms.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Hasher is implemented by each of the supported algorithms.
+type Hasher interface {
+	// Name of the algorithm.
+	Name() string
+
+	// Creates the hash of the password.
+	Hash(password []byte) ([]byte, error)
+
+	// Reports whether the hash was produced by the algorithm.
+	Recognizes(hashedPassword []byte) bool
+
+	// Compares the hash (which must be recognized by the algorithm)
+	// with its possible plaintext equivalent.
+	Verify(hashedPassword, password []byte) bool
+
+	// Reports whether the hash (which must be recognized by the
+	// algorithm) was produced with other parameters than the hasher's.
+	Outdated(hashedPassword []byte) bool
+}
+
+// Manager hashes the passwords with the preferred hasher
+// and verifies the hashes of every supported algorithm.
+type Manager struct {
+	preferred Hasher
+	hashers   []Hasher
+
+	// Called after every hash and verification, see `Manager.Observe`.
+	observer Observer
+}
+
+// Creates a manager hashing the passwords with the preferred hasher.
+func New(preferred Hasher) *Manager {
+	return &Manager{
+		preferred: preferred,
+		hashers:   []Hasher{preferred, Bcrypt{}, Argon2id{}},
+	}
+}
+
+// Creates a manager hashing the passwords with bcrypt
+// using the default cost, it's the historical behavior.
+func Default() *Manager {
+	return New(Bcrypt{Cost: BcryptDefaultCost})
+}
+
+// Sets the function called after every hash and verification,
+// it must be called before the manager is used.
+func (m *Manager) Observe(observer Observer) {
+	m.observer = observer
+}
+
+// Reports the operation started at start to the observer, if any.
+func (m *Manager) observe(hasher Hasher, operation string, start time.Time) {
+	if m.observer != nil {
+		m.observer(hasher.Name(), operation, time.Since(start))
+	}
+}
+
+// HashPassword creates the hash of the password with the preferred hasher.
+// On success it returns the hashed password and a nil error, and on failure,
+// it returns a nil hashed password and non-nil error.
+func (m *Manager) HashPassword(password []byte) ([]byte, error) {
+	start := time.Now()
+	defer m.observe(m.preferred, OperationHash, start)
+
+	return m.preferred.Hash(password)
+}
+
+// VerifyPassword compares a hashed password with its possible plaintext
+// equivalent. Returns true on success, or false on failure.
+//
+// On success it also reports whether the hash is outdated, that is,
+// it wasn't produced by the preferred hasher with its parameters, the
+// caller should replace it with a new hash of the password.
+func (m *Manager) VerifyPassword(hashedPassword, password []byte) (ok bool, outdated bool) {
+	for _, hasher := range m.hashers {
+		if !hasher.Recognizes(hashedPassword) {
+			continue
+		}
+
+		start := time.Now()
+		verified := hasher.Verify(hashedPassword, password)
+		m.observe(hasher, OperationVerify, start)
+
+		if !verified {
+			return false, false
+		}
+
+		outdated = hasher.Name() != m.preferred.Name() || m.preferred.Outdated(hashedPassword)
+
+		return true, outdated
+	}
+
+	return false, false
+}
@@ -0,0 +1,51 @@
+package hashing
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestManager(t *testing.T) {
+	cheapBcrypt := Bcrypt{Cost: bcrypt.MinCost}
+	cheapArgon2id := Argon2id{Time: 1, Memory: 1024, Threads: 1}
+
+	password := []byte("password#123")
+
+	bcryptHash, err := New(cheapBcrypt).HashPassword(password)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	argon2idHash, err := New(cheapArgon2id).HashPassword(password)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		manager  *Manager
+		hash     []byte
+		password string
+		ok       bool
+		outdated bool
+	}{
+		{"bcrypt", New(cheapBcrypt), bcryptHash, "password#123", true, false},
+		{"bcrypt wrong password", New(cheapBcrypt), bcryptHash, "wrong", false, false},
+		{"bcrypt other cost", New(Bcrypt{Cost: bcrypt.MinCost + 1}), bcryptHash, "password#123", true, true},
+		{"bcrypt to argon2id", New(cheapArgon2id), bcryptHash, "password#123", true, true},
+		{"argon2id", New(cheapArgon2id), argon2idHash, "password#123", true, false},
+		{"argon2id wrong password", New(cheapArgon2id), argon2idHash, "wrong", false, false},
+		{"argon2id other memory", New(Argon2id{Time: 1, Memory: 2048, Threads: 1}), argon2idHash, "password#123", true, true},
+		{"argon2id to bcrypt", New(cheapBcrypt), argon2idHash, "password#123", true, true},
+		{"unknown hash", New(cheapBcrypt), []byte("foo"), "foo", false, false},
+	}
+
+	for _, test := range tests {
+		ok, outdated := test.manager.VerifyPassword(test.hash, []byte(test.password))
+
+		if ok != test.ok || outdated != test.outdated {
+			t.Errorf("%s: expected ok=%t outdated=%t, got ok=%t outdated=%t", test.name, test.ok, test.outdated, ok, outdated)
+		}
+	}
+}
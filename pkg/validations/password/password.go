@@ -0,0 +1,187 @@
+// Package password validates the passwords chosen by the users against
+// a configurable policy, reporting every rule the password breaks.
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest password in bytes hashed by bcrypt, it ignores the bytes
+// past this length, so longer passwords are rejected instead of
+// truncated when bcrypt hashes the passwords (see `Policy.LimitBytes`).
+const BcryptMaxBytes = 72
+
+// Rules a password can break.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleMaxBytes  = "max_bytes"
+	RuleDigit     = "digit"
+	RuleLowercase = "lowercase"
+	RuleUppercase = "uppercase"
+	RuleSymbol    = "symbol"
+	RuleCommon    = "common"
+	RuleUserName  = "user_name"
+)
+
+//go:embed common.txt
+var commonFile string
+
+// Common passwords in lowercase.
+var common = parseCommon(commonFile)
+
+func parseCommon(file string) map[string]bool {
+	passwords := map[string]bool{}
+
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		passwords[strings.ToLower(line)] = true
+	}
+
+	return passwords
+}
+
+// Policy represents the rules the passwords must follow, the
+// lengths are counted in characters (not bytes).
+type Policy struct {
+	// Minimum number of characters.
+	MinLength int `json:"minLength"`
+
+	// Maximum number of characters.
+	MaxLength int `json:"maxLength"`
+
+	// Whether the password must have at least one digit.
+	RequireDigit bool `json:"requireDigit"`
+
+	// Whether the password must have at least one lowercase letter.
+	RequireLowercase bool `json:"requireLowercase"`
+
+	// Whether the password must have at least one uppercase letter.
+	RequireUppercase bool `json:"requireUppercase"`
+
+	// Whether the password must have at least one character
+	// that is neither a letter, a digit nor a space.
+	RequireSymbol bool `json:"requireSymbol"`
+
+	// Whether the common passwords (embedded in the binary) are rejected.
+	BanCommon bool `json:"banCommon"`
+
+	// Whether the passwords containing the name of the user are rejected.
+	BanUserName bool `json:"banUserName"`
+
+	// Maximum number of bytes, zero means no limit. It depends
+	// on the hashing algorithm, so it's not configurable.
+	maxBytes int
+}
+
+// Violation represents a rule broken by a password, as
+// reported to the user, see `Policy.Validate`.
+type Violation struct {
+	// One of the `Rule*` constants.
+	Rule string `json:"rule"`
+
+	// Description of the rule for the user.
+	Message string `json:"message"`
+}
+
+// Returns the policy used when none is configured.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:     8,
+		MaxLength:     64,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		BanCommon:     true,
+		BanUserName:   true,
+	}
+}
+
+// Returns a copy of the policy which also rejects the
+// passwords longer than maxBytes bytes.
+func (p Policy) LimitBytes(maxBytes int) Policy {
+	p.maxBytes = maxBytes
+	return p
+}
+
+// Returns the maximum number of bytes of the
+// passwords, zero means there is no limit.
+func (p Policy) MaxBytes() int {
+	return p.maxBytes
+}
+
+// Validates the password of the user named userName, it returns every
+// rule (the `Rule*` constants) the password breaks, or an empty slice
+// if it follows the policy.
+//
+// The password is validated as is, since it's hashed as is, so
+// the leading and trailing spaces are part of the password.
+func (p Policy) Validate(password string, userName string) []string {
+	rules := []string{}
+
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		rules = append(rules, RuleMinLength)
+	}
+
+	if length > p.MaxLength {
+		rules = append(rules, RuleMaxLength)
+	}
+
+	if p.maxBytes > 0 && len(password) > p.maxBytes {
+		rules = append(rules, RuleMaxBytes)
+	}
+
+	var hasDigit, hasLower, hasUpper, hasSymbol bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireDigit && !hasDigit {
+		rules = append(rules, RuleDigit)
+	}
+
+	if p.RequireLowercase && !hasLower {
+		rules = append(rules, RuleLowercase)
+	}
+
+	if p.RequireUppercase && !hasUpper {
+		rules = append(rules, RuleUppercase)
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		rules = append(rules, RuleSymbol)
+	}
+
+	if p.BanCommon && common[strings.ToLower(password)] {
+		rules = append(rules, RuleCommon)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(userName))
+
+	if p.BanUserName && name != "" && strings.Contains(strings.ToLower(password), name) {
+		rules = append(rules, RuleUserName)
+	}
+
+	return rules
+}
@@ -0,0 +1,43 @@
+package password
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	policy := DefaultPolicy().LimitBytes(BcryptMaxBytes)
+	policy.RequireUppercase = true
+
+	tests := []struct {
+		name     string
+		password string
+		userName string
+		rules    []string
+	}{
+		{"valid", "Secreto#123", "edwin", []string{}},
+		{"every class missing", "abcdefghi", "edwin", []string{RuleDigit, RuleUppercase, RuleSymbol}},
+		{"too short", "Ab#1", "edwin", []string{RuleMinLength}},
+		{"too long", "Ab#1" + strings.Repeat("a", 61), "edwin", []string{RuleMaxLength}},
+		{"too many bytes", "Ab#1" + strings.Repeat("ñ", 35), "edwin", []string{RuleMaxBytes}},
+		{"characters not bytes", "Añ#1ñññ", "edwin", []string{RuleMinLength}},
+		{"spaces are kept", " Ab#1   ", "edwin", []string{}},
+		{"common", "P@ssw0rd", "edwin", []string{RuleCommon}},
+		{"user name", "Edwin#2000", "edwin", []string{RuleUserName}},
+	}
+
+	for _, test := range tests {
+		rules := policy.Validate(test.password, test.userName)
+
+		if !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("%s: expected rules %v, got %v", test.name, test.rules, rules)
+		}
+	}
+
+	// Without a limit of bytes only the characters are counted.
+	rules := DefaultPolicy().Validate("Ab#1"+strings.Repeat("ñ", 35), "edwin")
+	if len(rules) != 0 {
+		t.Errorf("expected no rules without a limit of bytes, got %v", rules)
+	}
+}
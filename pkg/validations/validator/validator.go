@@ -0,0 +1,93 @@
+// Package validator collects the field-level errors of a payload, so
+// every invalid field is reported at once instead of the first one.
+package validator
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+// FieldError represents a field that failed a check.
+type FieldError struct {
+	// Name of the field as it appears in the JSON payload.
+	Field string `json:"field"`
+
+	// Code of the failed check.
+	Code codes.Code `json:"code"`
+}
+
+// Validator collects the errors of the checks performed on
+// a payload, only the first failed check of a field is kept.
+type Validator struct {
+	errors []FieldError
+}
+
+func New() *Validator {
+	return &Validator{
+		errors: []FieldError{},
+	}
+}
+
+// Records code as the error of field when ok is false,
+// unless the field already failed a previous check.
+func (v *Validator) Check(ok bool, field string, code codes.Code) {
+	if ok || v.hasError(field) {
+		return
+	}
+
+	v.errors = append(v.errors, FieldError{Field: field, Code: code})
+}
+
+func (v *Validator) hasError(field string) bool {
+	for _, fieldError := range v.errors {
+		if fieldError.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reports whether every check passed.
+func (v *Validator) Valid() bool {
+	return len(v.errors) == 0
+}
+
+// Returns the errors in the order the checks were performed.
+func (v *Validator) Errors() []FieldError {
+	return v.errors
+}
+
+// Returns the code of the first error, or nil if every
+// check passed, it's meant for callers reporting one error.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+
+	return v.errors[0].Code
+}
+
+// Reports whether value has characters other than spaces.
+func NotBlank(value string) bool {
+	return strings.TrimSpace(value) != ""
+}
+
+// Reports whether value has n characters (not bytes) or less.
+func MaxChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) <= n
+}
+
+// Reports whether value is a time formatted with layout.
+func MatchesLayout(value string, layout string) bool {
+	_, err := time.Parse(layout, value)
+	return err == nil
+}
+
+// Reports whether value is inside the closed range [min, max].
+func Between(value int, min int, max int) bool {
+	return value >= min && value <= max
+}
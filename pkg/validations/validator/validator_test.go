@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Edwing123/udem-chat-app/pkg/codes"
+)
+
+func TestValidator(t *testing.T) {
+	errEmpty := codes.NewCode("empty")
+	errTooLong := codes.NewCode("too_long")
+	errBadFormat := codes.NewCode("bad_format")
+
+	v := New()
+
+	if !v.Valid() || v.Err() != nil {
+		t.Fatalf("expected a valid validator, got errors %v", v.Errors())
+	}
+
+	v.Check(NotBlank("  "), "name", errEmpty)
+	v.Check(MaxChars("  ", 1), "name", errTooLong)
+	v.Check(MaxChars("ñññ", 3), "bio", errTooLong)
+	v.Check(MatchesLayout("01/01/2000", "2006-01-02"), "birthdate", errBadFormat)
+
+	expected := []FieldError{
+		{Field: "name", Code: errEmpty},
+		{Field: "birthdate", Code: errBadFormat},
+	}
+
+	if !reflect.DeepEqual(v.Errors(), expected) {
+		t.Errorf("expected errors %v, got %v", expected, v.Errors())
+	}
+
+	if v.Valid() || v.Err() != errEmpty {
+		t.Errorf("expected err %v, got %v", errEmpty, v.Err())
+	}
+}